@@ -0,0 +1,234 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+const sizeofBuf = unsafe.Sizeof(iouring_syscall.IOURingBuf{})
+
+// BufRing is a ring of buffers provided to the kernel for a buffer group,
+// the kernel selects buffers from the ring for the requests with the buffer group,
+// such as RecvMultishot, RecvSelect and ReadSelect.
+// The ring must be closed by Close to release the buffers.
+// It's safe for concurrent use by multiple goroutines.
+type BufRing struct {
+	lock sync.Mutex
+
+	iour    *IOURing
+	groupID uint16
+
+	ring []byte
+	mask uint16
+	tail uint16
+
+	// buffers keeps the provided buffers alive, indexed by buffer id,
+	// queued is the buffers in the ring which are not selected by the kernel yet
+	buffers map[uint16][]byte
+	queued  map[uint16]struct{}
+}
+
+// SetupBufferRing register a ring of entries buffers for the buffer group groupID,
+// entries must be a power of 2 and not greater than 32768.
+// IORING_REGISTER_PBUF_RING is available since 5.19
+func (iour *IOURing) SetupBufferRing(groupID uint16, entries int) (*BufRing, error) {
+	if entries <= 0 || entries > 1<<15 || entries&(entries-1) != 0 {
+		return nil, errors.New("entries of buffer ring must be a power of 2 and not greater than 32768")
+	}
+
+	// the ring must be page aligned, it's mapped instead of allocated by Go
+	ring, err := syscall.Mmap(
+		-1, 0, entries*int(sizeofBuf),
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANONYMOUS|syscall.MAP_PRIVATE,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := iouring_syscall.IOURingBufReg{
+		RingAddr:    uint64(uintptr(unsafe.Pointer(&ring[0]))),
+		RingEntries: uint32(entries),
+		Bgid:        groupID,
+	}
+	if err := iouring_syscall.IOURingRegister(
+		iour.fd,
+		iouring_syscall.IORING_REGISTER_PBUF_RING,
+		unsafe.Pointer(&reg),
+		1,
+	); err != nil {
+		_ = syscall.Munmap(ring)
+		return nil, err
+	}
+
+	bufRing := &BufRing{
+		iour:    iour,
+		groupID: groupID,
+		ring:    ring,
+		mask:    uint16(entries - 1),
+		buffers: make(map[uint16][]byte, entries),
+		queued:  make(map[uint16]struct{}, entries),
+	}
+	iour.bufRings.Store(groupID, bufRing)
+	return bufRing, nil
+}
+
+// GroupID returns the buffer group of the ring
+func (ring *BufRing) GroupID() uint16 {
+	return ring.groupID
+}
+
+// Provide add the buffer b with the buffer id bid to the ring,
+// ErrBufRingFull is returned if the entries of the ring are all in use
+func (ring *BufRing) Provide(bid uint16, b []byte) error {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if ring.ring == nil {
+		return ErrBufRingClosed
+	}
+	if _, ok := ring.queued[bid]; ok {
+		return errors.New("buffer is already in the ring")
+	}
+	if ring.full() {
+		return ErrBufRingFull
+	}
+
+	ring.buffers[bid] = b
+	ring.add(bid, b)
+	return nil
+}
+
+// Recycle add the buffer of the buffer id bid back to the ring
+// after the data selected by the kernel has been consumed
+func (ring *BufRing) Recycle(bid uint16) error {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if ring.ring == nil {
+		return ErrBufRingClosed
+	}
+
+	b, ok := ring.buffers[bid]
+	if !ok {
+		return errors.New("buffer is not provided")
+	}
+	if _, ok := ring.queued[bid]; ok {
+		return errors.New("buffer is already in the ring")
+	}
+	if ring.full() {
+		return ErrBufRingFull
+	}
+	ring.add(bid, b)
+	return nil
+}
+
+// Buffer returns the buffer of the buffer id bid,
+// the data of the request is Buffer(bid)[:n], n is returned by Result.ReturnInt
+func (ring *BufRing) Buffer(bid uint16) []byte {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	return ring.buffers[bid]
+}
+
+// Close unregister the ring, the buffers are no longer selected by the kernel.
+// ErrBufRingBusy is returned if there are in-flight requests of the buffer group,
+// the kernel may still write into the buffers selected by them
+func (ring *BufRing) Close() error {
+	// no request of the buffer group is submitted while the ring is closed
+	ring.iour.submitLock.Lock()
+	defer ring.iour.submitLock.Unlock()
+
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if ring.ring == nil {
+		return nil
+	}
+	if ring.iour.bufGroupInflight(ring.groupID) {
+		return ErrBufRingBusy
+	}
+
+	reg := iouring_syscall.IOURingBufReg{Bgid: ring.groupID}
+	if err := iouring_syscall.IOURingRegister(
+		ring.iour.fd,
+		iouring_syscall.IORING_UNREGISTER_PBUF_RING,
+		unsafe.Pointer(&reg),
+		1,
+	); err != nil {
+		return err
+	}
+
+	ring.iour.bufRings.Delete(ring.groupID)
+
+	err := syscall.Munmap(ring.ring)
+	ring.ring = nil
+	ring.buffers = nil
+	ring.queued = nil
+	return err
+}
+
+// bufGroupInflight returns whether there are in-flight requests selecting buffers from groupID
+func (iour *IOURing) bufGroupInflight(groupID uint16) bool {
+	iour.userDataLock.RLock()
+	defer iour.userDataLock.RUnlock()
+
+	for _, data := range iour.userDatas {
+		if data.bufSelect && data.bufGroup == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// selected take the buffer selected by the kernel out of the ring,
+// it's called by the completion loop before the result is notified
+func (ring *BufRing) selected(bid uint16) {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	delete(ring.queued, bid)
+}
+
+// full returns whether the entries of the ring are all in use
+func (ring *BufRing) full() bool {
+	return len(ring.queued) > int(ring.mask)
+}
+
+// add fill the entry at the tail of the ring and publish it to the kernel
+func (ring *BufRing) add(bid uint16, b []byte) {
+	var bp unsafe.Pointer
+	if len(b) > 0 {
+		bp = unsafe.Pointer(&b[0])
+	}
+
+	buf := (*iouring_syscall.IOURingBuf)(unsafe.Pointer(&ring.ring[uintptr(ring.tail&ring.mask)*sizeofBuf]))
+	buf.Addr = uint64(uintptr(bp))
+	buf.Len = uint32(len(b))
+	buf.Bid = bid
+
+	ring.queued[bid] = struct{}{}
+	ring.tail++
+	ring.storeTail()
+}
+
+// storeTail store the tail with release semantics, the tail overlays the resv field
+// of the first entry, it's stored with the bid field in a 32-bit word since
+// there is no 16-bit atomic operation
+func (ring *BufRing) storeTail() {
+	first := (*iouring_syscall.IOURingBuf)(unsafe.Pointer(&ring.ring[0]))
+	word := (*uint32)(unsafe.Pointer(&first.Bid))
+
+	v := atomic.LoadUint32(word)
+	(*[2]uint16)(unsafe.Pointer(&v))[1] = ring.tail
+	atomic.StoreUint32(word, v)
+}
@@ -0,0 +1,95 @@
+// +build linux
+
+package iouring
+
+import (
+	"syscall"
+	"testing"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+func TestBufRingRecvMultishot(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+
+	ring, err := iour.SetupBufferRing(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	for bid := uint16(0); bid < 2; bid++ {
+		if err := ring.Provide(bid, make([]byte, 16)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.Provide(2, make([]byte, 16)); err != ErrBufRingFull {
+		t.Fatalf("provide to the full ring: %v, want %v", err, ErrBufRingFull)
+	}
+	if err := ring.Recycle(0); err == nil {
+		t.Fatal("recycle the buffer which is not selected returns nil error")
+	}
+
+	ch := make(chan Result, 1)
+	if _, err := iour.SubmitRequest(RecvMultishot(fds[0], ring.GroupID(), 0), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	// the buffers are recycled, more receives than the buffers in the ring
+	for i := 0; i < 5; i++ {
+		msg := []byte{'a' + byte(i)}
+		if _, err := syscall.Write(fds[1], msg); err != nil {
+			t.Fatal(err)
+		}
+
+		result := <-ch
+		if result.Flags()&iouring_syscall.IORING_CQE_F_MORE == 0 {
+			t.Fatalf("multishot recv is completed: %v", result.Err())
+		}
+		n, err := result.ReturnInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bid, ok := result.BufferID()
+		if !ok {
+			t.Fatal("no buffer is selected")
+		}
+		if data := ring.Buffer(bid)[:n]; string(data) != string(msg) {
+			t.Fatalf("received %q, want %q", data, msg)
+		}
+		if err := ring.Recycle(bid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the kernel may still write into the buffers selected by the multishot recv
+	if err := ring.Close(); err != ErrBufRingBusy {
+		t.Fatalf("close the ring used by the multishot recv: %v, want %v", err, ErrBufRingBusy)
+	}
+
+	// the multishot recv is completed when the peer is closed
+	syscall.Close(fds[1])
+	result := <-ch
+	if result.Flags()&iouring_syscall.IORING_CQE_F_MORE != 0 {
+		t.Fatal("IORING_CQE_F_MORE is set on the last result")
+	}
+	if n, err := result.ReturnInt(); err != nil || n != 0 {
+		t.Fatalf("receive from the closed peer: %d, %v", n, err)
+	}
+
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Recycle(0); err != ErrBufRingClosed {
+		t.Fatalf("recycle to the closed ring: %v, want %v", err, ErrBufRingClosed)
+	}
+}
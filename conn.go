@@ -0,0 +1,257 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn is a net.Conn whose reads and writes are submitted to IOURing,
+// the deadlines are implemented by LinkTimeout.
+// It's safe for concurrent use by multiple goroutines.
+type Conn struct {
+	iour *IOURing
+	fd   int
+
+	laddr net.Addr
+	raddr net.Addr
+
+	lock          sync.Mutex
+	closed        bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	// reads and writes are the in-flight requests,
+	// they are canceled when the connection is closed or the deadline is changed,
+	// the value reports whether the request is submitted again after it's canceled
+	reads  map[Request]bool
+	writes map[Request]bool
+}
+
+// NewConn return a Conn of the connected socket fd,
+// the fd is owned by Conn and closed by Conn.Close
+func NewConn(iour *IOURing, fd int) (*Conn, error) {
+	sotype, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	lsa, err := syscall.Getsockname(fd)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockname", err)
+	}
+	rsa, err := syscall.Getpeername(fd)
+	if err != nil {
+		return nil, os.NewSyscallError("getpeername", err)
+	}
+
+	return &Conn{
+		iour:   iour,
+		fd:     fd,
+		laddr:  sockaddrToAddr(sotype, lsa),
+		raddr:  sockaddrToAddr(sotype, rsa),
+		reads:  make(map[Request]bool),
+		writes: make(map[Request]bool),
+	}, nil
+}
+
+// Fd returns the socket fd of the connection
+func (conn *Conn) Fd() int {
+	return conn.fd
+}
+
+// Read receive from the connection into b, io.EOF is returned if the peer closed the connection
+func (conn *Conn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	n, err := conn.submit(Recv(conn.fd, b, 0), true)
+	if err != nil {
+		return 0, conn.opError("read", err)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Write send b to the connection, the requests are submitted until b is sent or an error occurs
+func (conn *Conn) Write(b []byte) (int, error) {
+	var written int
+	for written < len(b) {
+		n, err := conn.submit(Send(conn.fd, b[written:], syscall.MSG_NOSIGNAL), false)
+		written += n
+		if err != nil {
+			return written, conn.opError("write", err)
+		}
+	}
+	return written, nil
+}
+
+// Close cancel the in-flight reads and writes and close the connection by a close request
+func (conn *Conn) Close() error {
+	conn.lock.Lock()
+	if conn.closed {
+		conn.lock.Unlock()
+		return conn.opError("close", ErrConnClosed)
+	}
+	conn.closed = true
+	requests := conn.pendingRequests(true, true, false)
+	conn.lock.Unlock()
+
+	for _, request := range requests {
+		_, _ = request.Cancel()
+	}
+
+	request, err := conn.iour.SubmitRequestSync(Close(conn.fd))
+	if err == nil {
+		err = request.Err()
+	}
+	if err != nil {
+		return conn.opError("close", err)
+	}
+	return nil
+}
+
+func (conn *Conn) LocalAddr() net.Addr {
+	return conn.laddr
+}
+
+func (conn *Conn) RemoteAddr() net.Addr {
+	return conn.raddr
+}
+
+// SetDeadline set the read and write deadlines, the zero value of t means no deadline
+func (conn *Conn) SetDeadline(t time.Time) error {
+	return conn.setDeadline(t, true, true)
+}
+
+// SetReadDeadline set the read deadline, the in-flight reads are canceled if t has passed,
+// otherwise they are submitted again with the new deadline
+func (conn *Conn) SetReadDeadline(t time.Time) error {
+	return conn.setDeadline(t, true, false)
+}
+
+// SetWriteDeadline set the write deadline, the in-flight writes are canceled if t has passed,
+// otherwise they are submitted again with the new deadline
+func (conn *Conn) SetWriteDeadline(t time.Time) error {
+	return conn.setDeadline(t, false, true)
+}
+
+func (conn *Conn) setDeadline(t time.Time, read, write bool) error {
+	conn.lock.Lock()
+	if conn.closed {
+		conn.lock.Unlock()
+		return conn.opError("set deadline", ErrConnClosed)
+	}
+	if read {
+		conn.readDeadline = t
+	}
+	if write {
+		conn.writeDeadline = t
+	}
+
+	// the LinkTimeout of the in-flight request cannot be updated,
+	// so the request is canceled and submitted again with the new deadline
+	rearm := t.IsZero() || t.After(time.Now())
+	requests := conn.pendingRequests(read, write, rearm)
+	conn.lock.Unlock()
+
+	for _, request := range requests {
+		_, _ = request.Cancel()
+	}
+	return nil
+}
+
+// submit submit the request linked with a LinkTimeout if there is a deadline,
+// and wait for the request to be completed. The request is submitted again
+// if it's canceled by SetDeadline to apply the new deadline
+func (conn *Conn) submit(prepRequest PrepRequest, read bool) (int, error) {
+	for {
+		conn.lock.Lock()
+		if conn.closed {
+			conn.lock.Unlock()
+			return 0, ErrConnClosed
+		}
+
+		deadline, pending := conn.writeDeadline, conn.writes
+		if read {
+			deadline, pending = conn.readDeadline, conn.reads
+		}
+
+		prepRequests := []PrepRequest{prepRequest}
+		if !deadline.IsZero() {
+			timeout := time.Until(deadline)
+			if timeout <= 0 {
+				conn.lock.Unlock()
+				return 0, os.ErrDeadlineExceeded
+			}
+			prepRequests = prepRequest.WithTimeout(timeout)
+		}
+
+		requests, err := conn.iour.SubmitRequests(prepRequests, nil)
+		if err != nil {
+			conn.lock.Unlock()
+			return 0, err
+		}
+		request := requests.Requests()[0]
+		pending[request] = false
+		conn.lock.Unlock()
+
+		<-request.Done()
+
+		conn.lock.Lock()
+		rearm := pending[request]
+		delete(pending, request)
+		closed := conn.closed
+		conn.lock.Unlock()
+
+		n, err := request.ReturnInt()
+		if err != ErrRequestCanceled {
+			return n, err
+		}
+
+		// request is canceled by Close, SetDeadline or the LinkTimeout
+		if closed {
+			return 0, ErrConnClosed
+		}
+		if !rearm {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// pendingRequests must be called with the lock held,
+// rearm marks the requests to be submitted again after they are canceled
+func (conn *Conn) pendingRequests(read, write, rearm bool) []Request {
+	var requests []Request
+	if read {
+		for request := range conn.reads {
+			conn.reads[request] = rearm
+			requests = append(requests, request)
+		}
+	}
+	if write {
+		for request := range conn.writes {
+			conn.writes[request] = rearm
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (conn *Conn) opError(op string, err error) error {
+	var network string
+	if conn.laddr != nil {
+		network = conn.laddr.Network()
+	}
+	return &net.OpError{Op: op, Net: network, Source: conn.laddr, Addr: conn.raddr, Err: err}
+}
@@ -0,0 +1,238 @@
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func dialConn(t *testing.T, iour *IOURing, addr net.Addr) *Conn {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prep, err := ConnectAddr(fd, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequestSync(prep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := NewConn(iour, fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func echoServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestConn(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln := echoServer(t)
+	defer ln.Close()
+
+	conn := dialConn(t, iour, ln.Addr())
+	if conn.RemoteAddr().String() != ln.Addr().String() {
+		t.Fatalf("remote address is %s, want %s", conn.RemoteAddr(), ln.Addr())
+	}
+	if conn.LocalAddr().Network() != "tcp" {
+		t.Fatalf("network of local address is %s, want tcp", conn.LocalAddr().Network())
+	}
+
+	msg := make([]byte, 1<<20)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(msg)
+		errCh <- err
+	}()
+
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != string(msg) {
+		t.Fatal("echoed message is not the sent message")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(buf); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("read from the closed connection: %v, want %v", err, ErrConnClosed)
+	}
+	if err := conn.Close(); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("close the closed connection: %v, want %v", err, ErrConnClosed)
+	}
+}
+
+func TestConnCloseCancelRead(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln := echoServer(t)
+	defer ln.Close()
+
+	conn := dialConn(t, iour, ln.Addr())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		errCh <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrConnClosed) {
+			t.Fatalf("read canceled by close: %v, want %v", err, ErrConnClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read is not canceled by close")
+	}
+}
+
+func TestConnReadDeadline(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln := echoServer(t)
+	defer ln.Close()
+
+	conn := dialConn(t, iour, ln.Addr())
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Read(make([]byte, 1))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read with deadline: %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatalf("read with deadline: %v is not a timeout", err)
+	}
+}
+
+func TestConnPendingReadDeadline(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln := echoServer(t)
+	defer ln.Close()
+
+	conn := dialConn(t, iour, ln.Addr())
+	defer conn.Close()
+
+	// the deadline set after the read is submitted applies to the pending read
+	time.AfterFunc(10*time.Millisecond, func() {
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	})
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read with pending deadline: %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	// the deadline is extended before it's exceeded, the pending read is not canceled
+	if err := conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, func() {
+		_ = conn.SetReadDeadline(time.Time{})
+	})
+	time.AfterFunc(100*time.Millisecond, func() {
+		_, _ = conn.Write([]byte("x"))
+	})
+	b := make([]byte, 1)
+	if n, err := conn.Read(b); err != nil || n != 1 || b[0] != 'x' {
+		t.Fatalf("read with extended deadline: %d, %q, %v", n, b[:n], err)
+	}
+}
+
+func TestConnZeroDeadline(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln := echoServer(t)
+	defer ln.Close()
+
+	conn := dialConn(t, iour, ln.Addr())
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("x")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("write with passed deadline: %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	// the zero deadline means no timeout
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = conn.Write([]byte("x"))
+	}()
+	b := make([]byte, 1)
+	if _, err := conn.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 'x' {
+		t.Fatalf("read %q, want %q", b, "x")
+	}
+}
@@ -5,7 +5,8 @@ package iouring
 import "errors"
 
 var (
-	ErrIOURingClosed = errors.New("iouring closed")
+	ErrIOURingClosed   = errors.New("iouring closed")
+	ErrIOURingDisabled = errors.New("iouring is disabled, it must be enabled by Enable")
 
 	ErrRequestCanceled     = errors.New("request is canceled")
 	ErrRequestNotFound     = errors.New("request is not found")
@@ -13,5 +14,16 @@ var (
 	ErrRequestNotCompleted = errors.New("request is not completed")
 	ErrNoRequestCallback   = errors.New("no request callback")
 
-	ErrUnregisteredFile = errors.New("file is unregistered")
+	ErrWaitTimeout = errors.New("wait for completion events timeout")
+
+	ErrUnregisteredFile   = errors.New("file is unregistered")
+	ErrFileSetFull        = errors.New("registered file set is full")
+	ErrUnregisteredBuffer = errors.New("buffer is unregistered")
+	ErrNotDirectIO        = errors.New("file is not opened with O_DIRECT")
+
+	ErrBufRingClosed = errors.New("buffer ring closed")
+	ErrBufRingFull   = errors.New("buffer ring is full")
+	ErrBufRingBusy   = errors.New("buffer ring is used by the in-flight requests")
+
+	ErrConnClosed = errors.New("use of closed network connection")
 )
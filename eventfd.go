@@ -3,7 +3,9 @@
 package iouring
 
 import (
+	"errors"
 	"os"
+	"syscall"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -24,3 +26,69 @@ func (iour *IOURing) registerEventfd() error {
 		unsafe.Pointer(&iour.eventfd), 1,
 	)
 }
+
+// Eventfd returns the eventfd signaled when the completion events are posted,
+// it's polled by the event loop of the caller if the IOURing is created
+// with WithExternalEventLoop, and it's closed by Close
+func (iour *IOURing) Eventfd() int {
+	return iour.eventfd
+}
+
+// ReapCompletions reap the completion events and complete their requests without blocking,
+// and return the number of the reaped completion events. It must be called when
+// the eventfd is readable if the IOURing is created with WithExternalEventLoop
+func (iour *IOURing) ReapCompletions() (int, error) {
+	if !iour.externalLoop {
+		return 0, errors.New("completion events are reaped by the run goroutine")
+	}
+
+	iour.reapLock.Lock()
+	defer iour.reapLock.Unlock()
+
+	select {
+	case <-iour.stop:
+		return 0, ErrIOURingClosed
+	default:
+	}
+
+	// clear the eventfd before reaping,
+	// the completion events posted after reaping signal it again
+	var buf [8]byte
+	_, _ = unix.Read(iour.eventfd, buf[:])
+
+	var reaped int
+	for {
+		n, err := iour.getCQEvents(iour.reapCQEs, false)
+		if err == syscall.EAGAIN {
+			return reaped, nil
+		}
+		if err != nil {
+			return reaped, err
+		}
+
+		for i := 0; i < n; i++ {
+			iour.complete(iour.reapCQEs[i])
+			iour.reapCQEs[i] = nil
+		}
+		iour.notifyCQEvents(n)
+		reaped += n
+	}
+}
+
+// reapInflight reap the completion events until the in-flight requests are completed,
+// the event loop of the caller may reap them concurrently
+func (iour *IOURing) reapInflight() {
+	fds := []unix.PollFd{{Fd: int32(iour.eventfd), Events: unix.POLLIN}}
+	for iour.hasInflight() {
+		if _, err := iour.ReapCompletions(); err != nil {
+			iour.logf("reap completions error: %v", err)
+			return
+		}
+		if !iour.hasInflight() {
+			return
+		}
+
+		// the eventfd may be cleared by the event loop of the caller, poll it with a timeout
+		_, _ = unix.Poll(fds, 10)
+	}
+}
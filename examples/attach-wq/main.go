@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/iceber/iouring-go"
+)
+
+func main() {
+	primary, err := iouring.New(8)
+	if err != nil {
+		panic(fmt.Sprintf("new IOURing error: %v", err))
+	}
+	defer primary.Close()
+
+	// every ring shares the asynchronous worker threads of the primary ring
+	iours := make([]*iouring.IOURing, runtime.NumCPU())
+	for i := range iours {
+		iour, err := iouring.New(8, iouring.WithAttachWQ(primary))
+		if err != nil {
+			panic(fmt.Sprintf("new IOURing attached to the primary error: %v", err))
+		}
+		defer iour.Close()
+		iours[i] = iour
+	}
+
+	var wg sync.WaitGroup
+	for i, iour := range iours {
+		wg.Add(1)
+		go func(i int, iour *iouring.IOURing) {
+			defer wg.Done()
+
+			request, err := iour.SubmitRequest(iouring.Timeout(100*time.Millisecond), nil)
+			if err != nil {
+				fmt.Printf("ring %d submit request error: %v\n", i, err)
+				return
+			}
+			<-request.Done()
+			if err := request.Err(); err != nil {
+				fmt.Printf("ring %d request error: %v\n", i, err)
+				return
+			}
+			fmt.Printf("ring %d timeout completed\n", i)
+		}(i, iour)
+	}
+	wg.Wait()
+}
@@ -46,11 +46,11 @@ func main() {
 			}
 			accept(result)
 
-		case iouring.OpRead:
-			read(result)
+		case iouring.OpRecv:
+			recv(result)
 
-		case iouring.OpWrite:
-			write(result)
+		case iouring.OpSend:
+			send(result)
 
 		case iouring.OpClose:
 			close(result)
@@ -70,36 +70,36 @@ func accept(result iouring.Result) {
 	fmt.Printf("Client Conn: %s\n", clientAddr)
 
 	buffer := make([]byte, readSize)
-	prep := iouring.Read(connFd, buffer).WithInfo(clientAddr)
+	prep := iouring.Recv(connFd, buffer, 0).WithInfo(clientAddr)
 	if _, err := iour.SubmitRequest(prep, resulter); err != nil {
-		panicf("submit read request error: %v", err)
+		panicf("submit recv request error: %v", err)
 	}
 }
 
-func read(result iouring.Result) {
+func recv(result iouring.Result) {
 	clientAddr := result.GetRequestInfo().(string)
 	if err := result.Err(); err != nil {
-		panicf("[%s] read error: %v", clientAddr, err)
+		panicf("[%s] recv error: %v", clientAddr, err)
 	}
 
 	num := result.ReturnValue0().(int)
 	buf, _ := result.GetRequestBuffer()
 	content := buf[:num]
 
-	connPrintf(clientAddr, "read byte: %v\ncontent: %s\n", num, content)
+	connPrintf(clientAddr, "recv byte: %v\ncontent: %s\n", num, content)
 
-	prep := iouring.Write(result.Fd(), content).WithInfo(clientAddr)
+	prep := iouring.Send(result.Fd(), content, 0).WithInfo(clientAddr)
 	if _, err := iour.SubmitRequest(prep, resulter); err != nil {
-		panicf("[%s] submit write request error: %v", clientAddr, err)
+		panicf("[%s] submit send request error: %v", clientAddr, err)
 	}
 }
 
-func write(result iouring.Result) {
+func send(result iouring.Result) {
 	clientAddr := result.GetRequestInfo().(string)
 	if err := result.Err(); err != nil {
-		panicf("[%s] write error: %v", clientAddr, err)
+		panicf("[%s] send error: %v", clientAddr, err)
 	}
-	connPrintf(clientAddr, "write successful\n")
+	connPrintf(clientAddr, "send successful\n")
 
 	prep := iouring.Close(result.Fd()).WithInfo(clientAddr)
 	if _, err := iour.SubmitRequest(prep, resulter); err != nil {
@@ -0,0 +1,126 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	_ io.Reader   = &File{}
+	_ io.Writer   = &File{}
+	_ io.ReaderAt = &File{}
+	_ io.WriterAt = &File{}
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// File is an io.Reader, io.Writer, io.ReaderAt and io.WriterAt whose reads and writes
+// are submitted to IOURing, the fd may be a registered file.
+// It's safe for concurrent use by multiple goroutines.
+type File struct {
+	iour *IOURing
+	fd   int
+
+	// offset is the offset of the sequential Read and Write,
+	// offsetLock serializes them
+	offsetLock sync.Mutex
+	offset     int64
+}
+
+// NewFile return a File of the fd, the fd is not owned by File.
+// Read and Write start at offset 0 regardless of the file offset of the fd
+func NewFile(iour *IOURing, fd int) *File {
+	return &File{iour: iour, fd: fd}
+}
+
+// Fd returns the fd of the file
+func (f *File) Fd() int {
+	return f.fd
+}
+
+// Read read up to len(b) bytes from the file at the current offset and advance the offset,
+// io.EOF is returned if the end of the file is reached
+func (f *File) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	f.offsetLock.Lock()
+	defer f.offsetLock.Unlock()
+
+	n, err := f.submit(Pread(f.fd, b, uint64(f.offset)))
+	if err != nil {
+		return 0, os.NewSyscallError("pread", err)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Write write b to the file at the current offset and advance the offset
+func (f *File) Write(b []byte) (int, error) {
+	f.offsetLock.Lock()
+	defer f.offsetLock.Unlock()
+
+	n, err := f.WriteAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt read len(b) bytes from the file starting at offset off,
+// the requests are submitted until b is filled, io.EOF is returned
+// with the partial count if the end of the file is reached
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.NewSyscallError("pread", errNegativeOffset)
+	}
+
+	var read int
+	for read < len(b) {
+		n, err := f.submit(Pread(f.fd, b[read:], uint64(off)+uint64(read)))
+		if err != nil {
+			return read, os.NewSyscallError("pread", err)
+		}
+		if n == 0 {
+			return read, io.EOF
+		}
+		read += n
+	}
+	return read, nil
+}
+
+// WriteAt write b to the file starting at offset off,
+// the requests are submitted until b is written or an error occurs
+func (f *File) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.NewSyscallError("pwrite", errNegativeOffset)
+	}
+
+	var written int
+	for written < len(b) {
+		n, err := f.submit(Pwrite(f.fd, b[written:], uint64(off)+uint64(written)))
+		if err != nil {
+			return written, os.NewSyscallError("pwrite", err)
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func (f *File) submit(prepRequest PrepRequest) (int, error) {
+	request, err := f.iour.SubmitRequestSync(prepRequest)
+	if err != nil {
+		return 0, err
+	}
+	return request.ReturnInt()
+}
@@ -0,0 +1,100 @@
+// +build linux
+
+package iouring
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileReadAtWriteAt(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	tmp, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	f := NewFile(iour, int(tmp.Fd()))
+
+	data := bytes.Repeat([]byte("iouring-go"), 1000)
+	if n, err := f.WriteAt(data, 10); err != nil || n != len(data) {
+		t.Fatalf("WriteAt returns %d, %v, want %d", n, err, len(data))
+	}
+
+	b := make([]byte, len(data))
+	if n, err := f.ReadAt(b, 10); err != nil || n != len(data) {
+		t.Fatalf("ReadAt returns %d, %v, want %d", n, err, len(data))
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatal("read data is not equal to the written data")
+	}
+
+	// reading across the end of the file returns the partial count and io.EOF
+	n, err := f.ReadAt(b, int64(len(data)))
+	if err != io.EOF || n != 10 {
+		t.Fatalf("ReadAt at the end returns %d, %v, want 10, %v", n, err, io.EOF)
+	}
+	if !bytes.Equal(b[:n], data[len(data)-10:]) {
+		t.Fatalf("partial read is %q", b[:n])
+	}
+
+	if _, err := f.ReadAt(b, -1); err == nil {
+		t.Fatal("ReadAt with negative offset returns nil error")
+	}
+}
+
+func TestFileCopy(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	src, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+
+	dst, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	data := make([]byte, 1<<20+123)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if _, err := io.Copy(NewFile(iour, int(src.Fd())), bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := io.Copy(NewFile(iour, int(dst.Fd())), NewFile(iour, int(src.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("copied %d bytes, want %d", n, len(data))
+	}
+
+	b, err := ioutil.ReadAll(NewFile(iour, int(dst.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatal("copied data is not equal to the source data")
+	}
+}
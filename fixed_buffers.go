@@ -9,17 +9,62 @@ import (
 	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
 
+// RegisterBuffers register bs with the kernel, the buffers are pinned once
+// and can be used by ReadFixed and WriteFixed with the index in bs
 func (iour *IOURing) RegisterBuffers(bs [][]byte) error {
 	if len(bs) == 0 {
 		return errors.New("buffer is empty")
 	}
 
+	iour.bufferLock.Lock()
+	defer iour.bufferLock.Unlock()
+
 	iovecs := bytes2iovec(bs)
 	bp := unsafe.Pointer(&iovecs[0])
 
-	return iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_REGISTER_BUFFERS, bp, uint32(len(iovecs)))
+	if err := iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_REGISTER_BUFFERS, bp, uint32(len(iovecs))); err != nil {
+		return err
+	}
+	iour.buffers = bs
+	return nil
 }
 
+// UnRegisterBuffers unregister the buffers.
+//
+// Deprecated: use UnregisterBuffers, which is named like UnregisterFiles
 func (iour *IOURing) UnRegisterBuffers() error {
-	return iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_UNREGISTER_BUFFERS, nil, 0)
+	return iour.UnregisterBuffers()
+}
+
+// UnregisterBuffers unregister the buffers registered by RegisterBuffers
+func (iour *IOURing) UnregisterBuffers() error {
+	iour.bufferLock.Lock()
+	defer iour.bufferLock.Unlock()
+
+	if err := iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_UNREGISTER_BUFFERS, nil, 0); err != nil {
+		return err
+	}
+	iour.buffers = nil
+	return nil
+}
+
+// isRegisteredBuffer returns whether b is within the registered buffer at index
+func (iour *IOURing) isRegisteredBuffer(index int, b []byte) bool {
+	iour.bufferLock.RLock()
+	defer iour.bufferLock.RUnlock()
+
+	if index >= len(iour.buffers) {
+		return false
+	}
+	if len(b) == 0 {
+		return true
+	}
+
+	buffer := iour.buffers[index]
+	if len(buffer) == 0 {
+		return false
+	}
+	start, end := uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&buffer[0]))+uintptr(len(buffer))
+	bstart := uintptr(unsafe.Pointer(&b[0]))
+	return bstart >= start && bstart+uintptr(len(b)) <= end
 }
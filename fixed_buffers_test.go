@@ -0,0 +1,98 @@
+// +build linux
+
+package iouring
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadWriteFixed(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	buffers := [][]byte{make([]byte, 16), make([]byte, 16)}
+	if err := iour.RegisterBuffers(buffers); err != nil {
+		t.Fatal(err)
+	}
+	defer iour.UnregisterBuffers()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	copy(buffers[0], "io with iouring")
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		WriteFixed(int(f.Fd()), buffers[0][:15], 0, 0),
+		ReadFixed(int(f.Fd()), buffers[1][1:], 1, 0),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+	if string(buffers[1][1:]) != "io with iouring" {
+		t.Fatalf("read %q, want %q", buffers[1][1:], "io with iouring")
+	}
+
+	if _, err := iour.SubmitRequest(ReadFixed(int(f.Fd()), make([]byte, 16), 0, 0), nil); err != ErrUnregisteredBuffer {
+		t.Fatalf("read into an unregistered buffer: %v, want %v", err, ErrUnregisteredBuffer)
+	}
+	if _, err := iour.SubmitRequest(ReadFixed(int(f.Fd()), buffers[0], 1, 0), nil); err != ErrUnregisteredBuffer {
+		t.Fatalf("read with a mismatched index: %v, want %v", err, ErrUnregisteredBuffer)
+	}
+	if _, err := iour.SubmitRequest(ReadFixed(int(f.Fd()), buffers[0], 2, 0), nil); err != ErrUnregisteredBuffer {
+		t.Fatalf("read with an out of range index: %v, want %v", err, ErrUnregisteredBuffer)
+	}
+}
+
+func benchmarkRead(b *testing.B, fixed bool) {
+	iour, err := New(8)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := os.Open("/dev/zero")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*1024)
+	if fixed {
+		if err := iour.RegisterBuffers([][]byte{buf}); err != nil {
+			b.Fatal(err)
+		}
+		defer iour.UnregisterBuffers()
+	}
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prep := Pread(int(f.Fd()), buf, 0)
+		if fixed {
+			prep = ReadFixed(int(f.Fd()), buf, 0, 0)
+		}
+		request, err := iour.SubmitRequest(prep, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		<-request.Done()
+		if _, err := request.ReturnInt(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRead(b *testing.B)      { benchmarkRead(b, false) }
+func BenchmarkReadFixed(b *testing.B) { benchmarkRead(b, true) }
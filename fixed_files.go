@@ -28,6 +28,42 @@ func (iour *IOURing) RegisterFiles(files []*os.File) error {
 	return iour.fileRegister.RegisterFiles(fds)
 }
 
+// RegisterFilesSparse register a file set of count sparse slots, the slots are filled by
+// RegisterFile as the files are registered and reclaimed when the files are
+// unregistered or closed by the Close request.
+// It must be called before any file is registered
+func (iour *IOURing) RegisterFilesSparse(count int) error {
+	if count <= 0 {
+		return errors.New("file set is empty")
+	}
+
+	fds := make([]int32, count)
+	for i := range fds {
+		fds[i] = -1
+	}
+	return iour.fileRegister.RegisterFiles(fds)
+}
+
+// UpdateFile replace the file at index of the registered file set,
+// the slot at index is made sparse if file is nil
+func (iour *IOURing) UpdateFile(index int, file *os.File) error {
+	fd := int32(-1)
+	if file != nil {
+		fd = int32(file.Fd())
+	}
+	return iour.fileRegister.UpdateFile(index, fd)
+}
+
+// UpdateFiles replace the fds from offset of the registered file set by IORING_REGISTER_FILES_UPDATE,
+// the slot is made sparse if the fd is -1
+func (iour *IOURing) UpdateFiles(offset int, fds []int) error {
+	vfds := make([]int32, 0, len(fds))
+	for _, fd := range fds {
+		vfds = append(vfds, int32(fd))
+	}
+	return iour.fileRegister.UpdateFiles(offset, vfds)
+}
+
 func (iour *IOURing) UnregisterFile(file *os.File) error {
 	return iour.fileRegister.UnregisterFile(int32(file.Fd()))
 }
@@ -41,6 +77,11 @@ func (iour *IOURing) UnregisterFiles(files []*os.File) error {
 	return iour.fileRegister.UnregisterFiles(fds)
 }
 
+// UnregisterAllFiles unregister the whole file set
+func (iour *IOURing) UnregisterAllFiles() error {
+	return iour.fileRegister.UnregisterAllFiles()
+}
+
 func (iour *IOURing) FileRegister() FileRegister {
 	return iour.fileRegister
 }
@@ -49,15 +90,24 @@ type FileRegister interface {
 	GetFileIndex(fd int32) (int, bool)
 	RegisterFile(fd int32) error
 	RegisterFiles(fds []int32) error
+	UpdateFile(index int, fd int32) error
+	UpdateFiles(offset int, fds []int32) error
 	UnregisterFile(fd int32) error
 	UnregisterFiles(fds []int32) error
+	UnregisterAllFiles() error
 }
 
+// defaultFileSetSize is the number of the slots of the file set registered by the first RegisterFile,
+// use RegisterFiles or RegisterFilesSparse to register a larger file set
+const defaultFileSetSize = 64
+
 type fileRegister struct {
 	lock      sync.Mutex
 	iouringFd int
 
-	fds          []int32
+	fds []int32
+
+	// sparseIndexs maps the start index of each run of sparse slots to the length of the run
 	sparseIndexs map[int]int
 
 	registered bool
@@ -86,7 +136,12 @@ func (register *fileRegister) register() error {
 		return err
 	}
 
+	register.sparseIndexs = make(map[int]int)
 	for i, fd := range register.fds {
+		if fd < 0 {
+			register.releaseIndex(i)
+			continue
+		}
 		register.indexs.Store(fd, i)
 	}
 	register.registered = true
@@ -94,115 +149,223 @@ func (register *fileRegister) register() error {
 }
 
 func (register *fileRegister) unregister() error {
-	return iouring_syscall.IOURingRegister(register.iouringFd, iouring_syscall.IORING_UNREGISTER_FILES, nil, 0)
+	if err := iouring_syscall.IOURingRegister(register.iouringFd, iouring_syscall.IORING_UNREGISTER_FILES, nil, 0); err != nil {
+		return err
+	}
+
+	for _, fd := range register.fds {
+		if fd >= 0 {
+			register.indexs.Delete(fd)
+		}
+	}
+	register.fds = nil
+	register.sparseIndexs = make(map[int]int)
+	register.registered = false
+	return nil
 }
 
+// sparses returns the number of the sparse slots
+func (register *fileRegister) sparses() int {
+	var n int
+	for _, sparse := range register.sparseIndexs {
+		n += sparse
+	}
+	return n
+}
+
+// RegisterFiles register fds, the fd -1 is registered as a sparse slot
+// which can be filled by RegisterFile or UpdateFile later.
+// Once the file set is registered, it cannot be extended without unregistering
+// the files used by the in-flight requests, so the fds are registered in the
+// sparse slots, and ErrFileSetFull is returned if there are not enough sparse slots
 func (register *fileRegister) RegisterFiles(fds []int32) error {
 	if len(fds) == 0 {
 		return errors.New("file set is empty")
 	}
 
-	vfds := make([]int32, 0, len(fds))
+	register.lock.Lock()
+	defer register.lock.Unlock()
+
+	if !register.registered {
+		vfds := make([]int32, 0, len(fds))
+		seen := make(map[int32]struct{}, len(fds))
+		for _, fd := range fds {
+			if fd >= 0 {
+				if _, ok := seen[fd]; ok {
+					continue
+				}
+				seen[fd] = struct{}{}
+			}
+			vfds = append(vfds, fd)
+		}
+
+		register.fds = vfds
+		return register.register()
+	}
+
+	unregistered := make([]int32, 0, len(fds))
+	seen := make(map[int32]struct{}, len(fds))
 	for _, fd := range fds {
 		if fd < 0 {
-			continue
+			return errors.New("sparse slots cannot be added to the registered file set")
 		}
-
-		if _, ok := register.indexs.Load(fd); ok {
+		if _, ok := seen[fd]; ok {
 			continue
 		}
-		vfds = append(vfds, fd)
+		seen[fd] = struct{}{}
+		if _, ok := register.GetFileIndex(fd); !ok {
+			unregistered = append(unregistered, fd)
+		}
+	}
+	if len(unregistered) == 0 {
+		return nil
+	}
+	if len(unregistered) > register.sparses() {
+		return ErrFileSetFull
 	}
 
-	if len(vfds) == 0 {
+	for _, fd := range unregistered {
+		fdi, _ := register.allocIndex()
+		register.fds[fdi] = fd
+		register.indexs.Store(fd, fdi)
+	}
+	return register.fresh(0, len(register.fds))
+}
+
+// RegisterFile register fd in a sparse slot, ErrFileSetFull is returned if there is no sparse slot.
+// If no file set is registered, a file set of defaultFileSetSize slots is registered
+func (register *fileRegister) RegisterFile(fd int32) error {
+	if fd < 0 {
 		return nil
 	}
-	fds = vfds
 
 	register.lock.Lock()
 	defer register.lock.Unlock()
 
-	if !register.registered {
-		register.fds = fds
-		return register.register()
+	if _, ok := register.GetFileIndex(fd); ok {
+		return nil
 	}
 
-	var fdi int
-	indexs := make(map[int32]int, len(fds))
-	updatedSpares := make(map[int]int, len(fds))
-	for i, spares := range register.sparseIndexs {
-		updatedSpares[i] = spares
-		for si := 0; si < spares; si++ {
-			for ; fdi < len(fds); fdi++ {
-				register.fds[i+si] = fds[fdi]
-				indexs[fds[fdi]] = i + si
-
-				fdi++
-				updatedSpares[i]--
-				break
-			}
-
-			if fdi >= len(fds) {
-				goto update
-			}
+	if !register.registered {
+		register.fds = make([]int32, defaultFileSetSize)
+		register.fds[0] = fd
+		for i := 1; i < len(register.fds); i++ {
+			register.fds[i] = -1
 		}
+		return register.register()
 	}
-	register.fds = append(register.fds, fds[fdi:]...)
 
-update:
-	if err := register.fresh(0, len(register.fds)); err != nil {
-		return err
+	fdi, ok := register.allocIndex()
+	if !ok {
+		return ErrFileSetFull
 	}
 
-	for i, spares := range updatedSpares {
-		if spares > 0 {
-			register.sparseIndexs[i] = spares
-			continue
-		}
-		delete(register.sparseIndexs, i)
-	}
-	for i, fd := range register.fds {
-		register.indexs.Store(fd, i)
+	register.fds[fdi] = fd
+	if err := register.fresh(fdi, 1); err != nil {
+		register.fds[fdi] = -1
+		register.releaseIndex(fdi)
+		return err
 	}
 
+	register.indexs.Store(fd, fdi)
 	return nil
 }
 
-func (register *fileRegister) RegisterFile(fd int32) error {
+// UpdateFile replace the fd at index of the registered file set,
+// the slot at index is made sparse if fd is -1
+func (register *fileRegister) UpdateFile(index int, fd int32) error {
 	if fd < 0 {
+		fd = -1
+	}
+
+	register.lock.Lock()
+	defer register.lock.Unlock()
+
+	if index < 0 || index >= len(register.fds) {
+		return errors.New("file index is out of range")
+	}
+	if fdi, ok := register.GetFileIndex(fd); ok && fdi != index {
+		return errors.New("file is already registered")
+	}
+
+	old := register.fds[index]
+	if old == fd {
 		return nil
 	}
 
-	if _, ok := register.GetFileIndex(fd); ok {
+	register.fds[index] = fd
+	if err := register.fresh(index, 1); err != nil {
+		register.fds[index] = old
+		return err
+	}
+
+	if old < 0 {
+		register.takeIndex(index)
+	} else {
+		register.indexs.Delete(old)
+	}
+	if fd < 0 {
+		register.releaseIndex(index)
+	} else {
+		register.indexs.Store(fd, index)
+	}
+	return nil
+}
+
+// UpdateFiles replace the fds from offset of the registered file set in one update,
+// the slot is made sparse if the fd is -1. The fds can be moved between the replaced slots,
+// but they cannot be registered at the other slots
+func (register *fileRegister) UpdateFiles(offset int, fds []int32) error {
+	if len(fds) == 0 {
 		return nil
 	}
 
 	register.lock.Lock()
 	defer register.lock.Unlock()
 
-	if !register.registered {
-		register.fds = []int32{fd}
-		return register.register()
+	if offset < 0 || offset+len(fds) > len(register.fds) {
+		return errors.New("file index is out of range")
 	}
 
-	var fdi int
-	var spares int
-	for fdi, spares = range register.sparseIndexs {
-		break
+	vfds := make([]int32, len(fds))
+	seen := make(map[int32]struct{}, len(fds))
+	for i, fd := range fds {
+		if fd < 0 {
+			vfds[i] = -1
+			continue
+		}
+		if _, ok := seen[fd]; ok {
+			return errors.New("file is updated at multiple indexes")
+		}
+		seen[fd] = struct{}{}
+		if fdi, ok := register.GetFileIndex(fd); ok && (fdi < offset || fdi >= offset+len(fds)) {
+			return errors.New("file is already registered")
+		}
+		vfds[i] = fd
 	}
-	register.fds[fdi] = fd
 
-	if err := register.fresh(fdi, 1); err != nil {
+	olds := make([]int32, len(fds))
+	copy(olds, register.fds[offset:])
+	copy(register.fds[offset:], vfds)
+	if err := register.fresh(offset, len(vfds)); err != nil {
+		copy(register.fds[offset:], olds)
 		return err
 	}
 
-	if spares == 1 {
-		delete(register.sparseIndexs, fdi)
-	} else {
-		register.sparseIndexs[fdi]--
+	for i, old := range olds {
+		if old < 0 {
+			register.takeIndex(offset + i)
+		} else {
+			register.indexs.Delete(old)
+		}
+	}
+	for i, fd := range vfds {
+		if fd < 0 {
+			register.releaseIndex(offset + i)
+		} else {
+			register.indexs.Store(fd, offset+i)
+		}
 	}
-
-	register.indexs.Store(fd, fdi)
 	return nil
 }
 
@@ -238,13 +401,41 @@ func (register *fileRegister) UnregisterFiles(fds []int32) error {
 		}
 		unregistered = true
 	}
-	if unregistered {
+	if !unregistered {
 		return nil
 	}
 
 	return register.fresh(0, len(register.fds))
 }
 
+func (register *fileRegister) UnregisterAllFiles() error {
+	register.lock.Lock()
+	defer register.lock.Unlock()
+
+	if !register.registered {
+		return nil
+	}
+	return register.unregister()
+}
+
+// closeFile returns the function removing fd from the register after it is closed by the close request,
+// requests submitted before still use the registered file until the close request is completed.
+// The register is not changed until then, so the close request can be rolled back
+func (register *fileRegister) closeFile(fd int32) func() {
+	if _, ok := register.GetFileIndex(fd); !ok {
+		return nil
+	}
+
+	return func() {
+		register.lock.Lock()
+		defer register.lock.Unlock()
+
+		if fdi, ok := register.deleteFile(fd); ok {
+			_ = register.fresh(fdi, 1)
+		}
+	}
+}
+
 func (register *fileRegister) deleteFile(fd int32) (fdi int, ok bool) {
 	var v interface{}
 	/*
@@ -260,20 +451,52 @@ func (register *fileRegister) deleteFile(fd int32) (fdi int, ok bool) {
 
 	fdi = v.(int)
 	register.fds[fdi] = -1
+	register.releaseIndex(fdi)
+	return
+}
 
-	var updated bool
+// releaseIndex add the slot at fdi to the sparse slots, merging it with the adjacent runs
+func (register *fileRegister) releaseIndex(fdi int) {
+	start, spares := fdi, 1
+	if next, ok := register.sparseIndexs[fdi+1]; ok {
+		delete(register.sparseIndexs, fdi+1)
+		spares += next
+	}
 	for i, sparse := range register.sparseIndexs {
 		if i+sparse == fdi {
-			register.sparseIndexs[i]++
-			updated = true
+			start, spares = i, sparse+spares
 			break
 		}
 	}
+	register.sparseIndexs[start] = spares
+}
+
+// takeIndex remove the slot at index from the sparse slots
+func (register *fileRegister) takeIndex(index int) bool {
+	for i, sparse := range register.sparseIndexs {
+		if index < i || index >= i+sparse {
+			continue
+		}
+
+		delete(register.sparseIndexs, i)
+		if index > i {
+			register.sparseIndexs[i] = index - i
+		}
+		if index+1 < i+sparse {
+			register.sparseIndexs[index+1] = i + sparse - index - 1
+		}
+		return true
+	}
+	return false
+}
 
-	if !updated {
-		register.sparseIndexs[fdi] = 1
+// allocIndex take a sparse slot, returns false if there is no sparse slot
+func (register *fileRegister) allocIndex() (int, bool) {
+	for i := range register.sparseIndexs {
+		register.takeIndex(i)
+		return i, true
 	}
-	return
+	return -1, false
 }
 
 func (register *fileRegister) fresh(offset int, length int) error {
@@ -285,6 +508,6 @@ func (register *fileRegister) fresh(offset int, length int) error {
 		register.iouringFd,
 		iouring_syscall.IORING_REGISTER_FILES_UPDATE,
 		unsafe.Pointer(&update),
-		uint32(len(register.fds)),
+		uint32(length),
 	)
 }
@@ -0,0 +1,287 @@
+// +build linux
+
+package iouring
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestFileRegister(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	files := make([]*os.File, 4)
+	for i := range files {
+		if files[i], err = os.Open("/dev/zero"); err != nil {
+			t.Fatal(err)
+		}
+		defer files[i].Close()
+	}
+	register := iour.FileRegister()
+
+	checkIndex := func(file *os.File, index int) {
+		t.Helper()
+		i, ok := iour.GetFixedFileIndex(file)
+		if index < 0 {
+			if ok {
+				t.Fatalf("file %d is registered at %d", file.Fd(), i)
+			}
+			return
+		}
+		if !ok || i != index {
+			t.Fatalf("file %d is registered at %d(%t), want %d", file.Fd(), i, ok, index)
+		}
+
+		buf := []byte{1}
+		request, err := iour.SubmitRequest(Read(int(file.Fd()), buf), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-request.Done()
+		if _, err := request.ReturnInt(); err != nil {
+			t.Fatalf("read the registered file %d: %v", file.Fd(), err)
+		}
+	}
+
+	if err := register.RegisterFiles([]int32{int32(files[0].Fd()), -1, -1}); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[0], 0)
+
+	// fill the sparse slots
+	if err := iour.RegisterFile(files[1]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[1], 1)
+	if err := iour.RegisterFile(files[2]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[2], 2)
+
+	// no sparse slot is left
+	if err := iour.RegisterFile(files[3]); err != ErrFileSetFull {
+		t.Fatalf("register a file to the full file set: %v, want %v", err, ErrFileSetFull)
+	}
+	if err := iour.RegisterFiles([]*os.File{files[3]}); err != ErrFileSetFull {
+		t.Fatalf("register files to the full file set: %v, want %v", err, ErrFileSetFull)
+	}
+	if err := register.RegisterFiles([]int32{-1}); err == nil {
+		t.Fatal("add sparse slots to the registered file set")
+	}
+	checkIndex(files[0], 0)
+	checkIndex(files[1], 1)
+	checkIndex(files[3], -1)
+
+	if err := iour.UpdateFile(1, files[3]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[1], -1)
+	checkIndex(files[3], 1)
+	if err := iour.UpdateFile(0, files[3]); err == nil {
+		t.Fatal("update a registered file to another index")
+	}
+	if err := iour.UpdateFile(3, files[1]); err == nil {
+		t.Fatal("update an index out of range")
+	}
+
+	if err := iour.UnregisterFiles([]*os.File{files[0], files[2]}); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[0], -1)
+	checkIndex(files[2], -1)
+	checkIndex(files[3], 1)
+
+	// reuse the sparse slots
+	if err := iour.RegisterFiles([]*os.File{files[0], files[1]}); err != nil {
+		t.Fatal(err)
+	}
+	index0, _ := iour.GetFixedFileIndex(files[0])
+	index1, _ := iour.GetFixedFileIndex(files[1])
+	if index0+index1 != 2 {
+		t.Fatalf("files are registered at %d and %d, want 0 and 2", index0, index1)
+	}
+	checkIndex(files[0], index0)
+	checkIndex(files[1], index1)
+
+	if err := iour.UpdateFile(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[3], -1)
+	if err := iour.RegisterFile(files[3]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[3], 1)
+
+	if err := iour.UnregisterAllFiles(); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		checkIndex(file, -1)
+	}
+	if err := iour.RegisterFile(files[2]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(files[2], 0)
+}
+
+func TestRegisterFilesSparse(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if err := iour.RegisterFilesSparse(0); err == nil {
+		t.Fatal("register zero sparse slots returns nil error")
+	}
+	if err := iour.RegisterFilesSparse(2); err != nil {
+		t.Fatal(err)
+	}
+	register := iour.FileRegister()
+
+	// the fds are closed by the ring, so they are not owned by os.File
+	open := func() int {
+		fd, err := syscall.Open("/dev/zero", syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := register.RegisterFile(int32(fd)); err != nil {
+			t.Fatal(err)
+		}
+		return fd
+	}
+
+	fd0, fd1 := open(), open()
+	defer syscall.Close(fd1)
+	if index, ok := register.GetFileIndex(int32(fd1)); !ok || index != 1 {
+		t.Fatalf("fd is registered at %d(%t), want 1", index, ok)
+	}
+
+	// the slot of the fd closed by the ring is reclaimed
+	request, err := iour.SubmitRequestSync(Close(fd0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	fd2 := open()
+	defer syscall.Close(fd2)
+	if index, ok := register.GetFileIndex(int32(fd2)); !ok || index != 0 {
+		t.Fatalf("fd is registered at %d(%t), want the reclaimed slot 0", index, ok)
+	}
+}
+
+func TestUpdateFiles(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	files := make([]*os.File, 3)
+	for i := range files {
+		if files[i], err = os.Open("/dev/zero"); err != nil {
+			t.Fatal(err)
+		}
+		defer files[i].Close()
+	}
+	fd0, fd1, fd2 := int(files[0].Fd()), int(files[1].Fd()), int(files[2].Fd())
+
+	if err := iour.RegisterFilesSparse(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.UpdateFiles(0, []int{fd0, -1, fd1}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkIndexs := func(indexs ...int) {
+		t.Helper()
+		for i, index := range indexs {
+			fdi, ok := iour.GetFixedFileIndex(files[i])
+			if index < 0 && ok || index >= 0 && fdi != index {
+				t.Fatalf("file %d is registered at %d(%t), want %d", i, fdi, ok, index)
+			}
+		}
+	}
+	checkIndexs(0, 2, -1)
+
+	// move the files between the updated slots
+	if err := iour.UpdateFiles(0, []int{fd1, fd2, fd0}); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexs(2, 0, 1)
+
+	if err := iour.UpdateFiles(3, []int{fd0}); err == nil {
+		t.Fatal("update a registered file to another index")
+	}
+	if err := iour.UpdateFiles(2, []int{-1, -1, -1}); err == nil {
+		t.Fatal("update the indexes out of range")
+	}
+	if err := iour.UpdateFiles(1, []int{fd2, fd2}); err == nil {
+		t.Fatal("update a file at multiple indexes")
+	}
+	checkIndexs(2, 0, 1)
+
+	// the sparse slots are reused by RegisterFile
+	if err := iour.UpdateFiles(0, []int{-1, -1}); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexs(2, -1, -1)
+	if err := iour.RegisterFiles([]*os.File{files[1], files[2]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.RegisterFile(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.RegisterFiles([]*os.File{files[0], files[1], files[2]}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseRegisteredFile(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fd, err := syscall.Open("/dev/zero", syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.FileRegister().RegisterFile(int32(fd)); err != nil {
+		syscall.Close(fd)
+		t.Fatal(err)
+	}
+
+	// the close request is rolled back with the failed request, fd is still registered
+	if _, err := iour.SubmitRequests([]PrepRequest{
+		Close(fd),
+		ReadFixed(fd, make([]byte, 1), 0, 0),
+	}, nil); err != ErrUnregisteredBuffer {
+		syscall.Close(fd)
+		t.Fatalf("submit with unregistered buffer: %v, want %v", err, ErrUnregisteredBuffer)
+	}
+	if _, ok := iour.FileRegister().GetFileIndex(int32(fd)); !ok {
+		syscall.Close(fd)
+		t.Fatal("file is unregistered by the rolled back close request")
+	}
+
+	request, err := iour.SubmitRequestSync(Close(fd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := iour.FileRegister().GetFileIndex(int32(fd)); ok {
+		t.Fatal("file is still registered after the close request is completed")
+	}
+}
@@ -4,12 +4,17 @@
 package iouring
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
@@ -17,12 +22,25 @@ import (
 // IOURing contains iouring_syscall submission and completion queue.
 // It's safe for concurrent use by multiple goroutines.
 type IOURing struct {
+	// userDataID is the last allocated request id,
+	// must be 64-bit aligned for atomic operations on 32-bit platforms
+	userDataID uint64
+
+	// cqOverflows is the number of times the completion queue overflow is observed
+	cqOverflows uint64
+
 	params *iouring_syscall.IOURingParams
 	fd     int
 
 	eventfd int
 	cqeSign chan struct{}
 
+	// externalLoop is true if the completion events are reaped by ReapCompletions
+	// instead of the run goroutine, reapLock serializes the reaping
+	externalLoop bool
+	reapLock     sync.Mutex
+	reapCQEs     []iouring_syscall.CompletionQueueEvent
+
 	sq *SubmissionQueue
 	cq *CompletionQueue
 
@@ -32,14 +50,47 @@ type IOURing struct {
 	Features uint32
 
 	submitLock sync.Mutex
+	// disabled is true until the ring created by WithDisableRing is enabled,
+	// it's protected by submitLock
+	disabled bool
 
 	userDataLock sync.RWMutex
 	userDatas    map[uint64]*UserData
 
 	fileRegister FileRegister
 
+	bufferLock sync.RWMutex
+	buffers    [][]byte
+
+	// bufRings maps the buffer group to the *BufRing,
+	// the buffers selected by the kernel are taken out of the ring
+	bufRings sync.Map
+
+	// cqEvents is the number of the reaped completion events,
+	// cqEventsSign is closed when more completion events are reaped
+	cqEventsLock sync.Mutex
+	cqEvents     uint64
+	cqEventsSign chan struct{}
+
+	// cancelFlags is whether the IORING_ASYNC_CANCEL_* flags are supported,
+	// it's probed by CancelAll or CancelFd until a probe is completed
+	cancelFlags int32
+
+	logger *log.Logger
+	debug  bool
+
+	// errs receives the fatal error of the run goroutine,
+	// it's closed when the run goroutine exits
+	errs chan error
+
+	// optionErr is the first error of the invalid IOURingOption
+	optionErr error
+
+	// closer is closed when Close is called, stop is closed to stop the run goroutine
+	// after the in-flight requests are drained, closed is closed after it exits
 	fdclosed bool
 	closer   chan struct{}
+	stop     chan struct{}
 	closed   chan struct{}
 }
 
@@ -49,13 +100,30 @@ func New(entries uint, opts ...IOURingOption) (*IOURing, error) {
 		params:    &iouring_syscall.IOURingParams{},
 		userDatas: make(map[uint64]*UserData),
 		cqeSign:   make(chan struct{}, 1),
+		logger:    log.New(ioutil.Discard, "", 0),
 		closer:    make(chan struct{}),
+		stop:      make(chan struct{}),
 		closed:    make(chan struct{}),
+		errs:      make(chan error, 1),
 	}
 
 	for _, opt := range opts {
 		opt(iour)
 	}
+	if iour.optionErr != nil {
+		return nil, iour.optionErr
+	}
+	if err := validateSetupFlags(iour.params.Flags); err != nil {
+		return nil, err
+	}
+	if iour.externalLoop && iour.params.Flags&iouring_syscall.IORING_SETUP_IOPOLL != 0 {
+		return nil, errors.New("IOPOLL requires the completions to be polled by the run goroutine")
+	}
+	if iour.params.Flags&iouring_syscall.IORING_SETUP_CQSIZE != 0 &&
+		uint(iour.params.CQEntries) < roundupPow2(entries) {
+		return nil, fmt.Errorf("completion queue size %d is less than the submission queue entries %d",
+			iour.params.CQEntries, roundupPow2(entries))
+	}
 
 	var err error
 	iour.fd, err = iouring_syscall.IOURingSetup(entries, iour.params)
@@ -74,18 +142,28 @@ func New(entries uint, opts ...IOURingOption) (*IOURing, error) {
 	}
 	iour.Flags = iour.params.Flags
 	iour.Features = iour.params.Features
+	iour.disabled = iour.Flags&iouring_syscall.IORING_SETUP_R_DISABLED != 0
+
+	if iour.externalLoop {
+		iour.reapCQEs = make([]iouring_syscall.CompletionQueueEvent, *iour.cq.entries)
+	} else {
+		// run goroutine must be started before any call to iour.Close,
+		// otherwise Close will wait for it forever
+		go iour.run()
+	}
 
 	if err := iour.registerEventfd(); err != nil {
 		iour.Close()
 		return nil, err
 	}
 
-	if err := registerIOURing(iour); err != nil {
-		iour.Close()
-		return nil, err
+	if !iour.externalLoop {
+		if err := registerIOURing(iour); err != nil {
+			iour.Close()
+			return nil, err
+		}
 	}
 
-	go iour.run()
 	return iour, nil
 }
 
@@ -94,26 +172,86 @@ func (iour *IOURing) Size() int {
 	return int(*iour.sq.entries)
 }
 
-// Close IOURing
+// Stats contains the statistics of the completion queue
+type Stats struct {
+	// CQOverflows is the number of times the completion queue overflow is observed,
+	// the completion events that don't fit in the completion queue are kept
+	// in the overflow list of the kernel and flushed by io_uring_enter
+	CQOverflows uint64
+
+	// CQDropped is the number of completion events dropped by the kernel,
+	// they are lost when the overflow list can't be allocated,
+	// or the kernel doesn't support IORING_FEAT_NODROP
+	CQDropped uint32
+}
+
+// Stats returns the statistics of the completion queue,
+// a larger completion queue by WithCQSize avoids the overflow
+func (iour *IOURing) Stats() Stats {
+	return Stats{
+		CQOverflows: atomic.LoadUint64(&iour.cqOverflows),
+		CQDropped:   atomic.LoadUint32(iour.cq.overflow),
+	}
+}
+
+// Close cancel the in-flight requests and wait for them to be completed by the kernel,
+// so that the kernel doesn't use their buffers anymore, then close IOURing.
+// The in-flight requests are completed with ErrIOURingClosed, their results are
+// notified via channels without blocking Close
 func (iour *IOURing) Close() error {
 	iour.submitLock.Lock()
-	defer iour.submitLock.Unlock()
-
 	select {
 	case <-iour.closer:
 	default:
 		close(iour.closer)
+
+		iour.userDataLock.RLock()
+		userDatas := make([]*UserData, 0, len(iour.userDatas))
+		for _, data := range iour.userDatas {
+			userDatas = append(userDatas, data)
+		}
+		iour.userDataLock.RUnlock()
+		iour.cancelUserDatas(userDatas)
+	}
+	iour.submitLock.Unlock()
+
+	if iour.externalLoop {
+		iour.reapInflight()
+	} else {
+		iour.waitInflight()
+	}
+
+	iour.submitLock.Lock()
+	defer iour.submitLock.Unlock()
+
+	select {
+	case <-iour.stop:
+	default:
+		if iour.externalLoop {
+			// wait for the reaping of the event loop, ReapCompletions returns
+			// ErrIOURingClosed after stop is closed. There is no run goroutine to close the channels
+			iour.reapLock.Lock()
+			close(iour.stop)
+			iour.reapLock.Unlock()
+			close(iour.errs)
+			close(iour.closed)
+		} else {
+			close(iour.stop)
+		}
 	}
 
 	if iour.eventfd > 0 {
-		if err := removeIOURing(iour); err != nil {
-			return err
+		if !iour.externalLoop {
+			if err := removeIOURing(iour); err != nil {
+				return err
+			}
 		}
 		syscall.Close(iour.eventfd)
 		iour.eventfd = -1
 	}
 
 	<-iour.closed
+	iour.terminateRequests()
 
 	if err := munmapIOURing(iour); err != nil {
 		return err
@@ -129,6 +267,29 @@ func (iour *IOURing) Close() error {
 	return nil
 }
 
+// waitInflight wait until the in-flight requests are completed,
+// or the run goroutine exits by the fatal error
+func (iour *IOURing) waitInflight() {
+	for {
+		iour.cqEventsLock.Lock()
+		if !iour.hasInflight() {
+			iour.cqEventsLock.Unlock()
+			return
+		}
+		if iour.cqEventsSign == nil {
+			iour.cqEventsSign = make(chan struct{})
+		}
+		sign := iour.cqEventsSign
+		iour.cqEventsLock.Unlock()
+
+		select {
+		case <-sign:
+		case <-iour.closed:
+			return
+		}
+	}
+}
+
 // IsClosed IOURing is closed
 func (iour *IOURing) IsClosed() (closed bool) {
 	select {
@@ -145,6 +306,26 @@ func (iour *IOURing) getSQEntry() iouring_syscall.SubmissionQueueEntry {
 		if sqe != nil {
 			return sqe
 		}
+		iour.waitSQEntry()
+	}
+}
+
+// waitSQEntry wait for the kernel to consume submission queue entries,
+// must be called with the submit lock held
+func (iour *IOURing) waitSQEntry() {
+	if iour.Flags&iouring_syscall.IORING_SETUP_SQPOLL != 0 {
+		// blocks until the kernel poller thread has consumed at least one entry,
+		// available since 5.13
+		_, err := iouring_syscall.IOURingEnter(iour.fd, 0, 0, iouring_syscall.IORING_ENTER_FLAGS_SQ_WAIT, nil)
+		if err == nil {
+			return
+		}
+	}
+
+	// without SQPoll, the queue is full of the entries which are not submitted yet,
+	// submit them instead of spinning until they are consumed.
+	// On older kernels, the submission wakes up the sleeping poller thread
+	if _, err := iour.submit(); err != nil || iour.Flags&iouring_syscall.IORING_SETUP_SQPOLL != 0 {
 		runtime.Gosched()
 	}
 }
@@ -156,26 +337,51 @@ func (iour *IOURing) doRequest(sqe iouring_syscall.SubmissionQueueEntry, request
 	userData.setOpcode(sqe.Opcode())
 
 	sqe.SetUserData(userData.id)
+	if sqe.Flags()&iouring_syscall.IOSQE_FLAGS_BUFFER_SELECT != 0 {
+		// the buffer group shares the field with the buffer index
+		userData.bufSelect = true
+		userData.bufGroup = sqe.BufIndex()
+	}
 
 	userData.request.fd = int(sqe.Fd())
-	if sqe.Fd() >= 0 {
+	if isFdNumber(sqe.Opcode()) {
+		userData.request.fd = -1
+	}
+
+	if sqe.Opcode() == iouring_syscall.IORING_OP_CLOSE {
+		if register, ok := iour.fileRegister.(*fileRegister); ok {
+			userData.completed = register.closeFile(sqe.Fd())
+		}
+	} else if sqe.Fd() >= 0 && canUseFixedFile(sqe.Opcode()) {
 		if index, ok := iour.fileRegister.GetFileIndex(int32(sqe.Fd())); ok {
 			sqe.SetFdIndex(int32(index))
 		} else if iour.Flags&iouring_syscall.IORING_SETUP_SQPOLL != 0 &&
 			iour.Features&iouring_syscall.IORING_FEAT_SQPOLL_NONFIXED == 0 {
 			/*
-				Before version 5.10 of the Linux kernel, to successful use SQPoll, the application
+				Before version 5.11 of the Linux kernel, to successful use SQPoll, the application
 				must register a set of files to be used for IO through iour.RegisterFiles.
 				Failure to do so will result in submitted IO being errored with EBADF
 
 				The presence of this feature can be detected by the IORING_FEAT_SQPOLL_NONFIXED
-				In Version 5.10 and later, it is no longer necessary to register files to use SQPoll
+				In Version 5.11 and later, it is no longer necessary to register files to use SQPoll
 			*/
 
 			return nil, ErrUnregisteredFile
 		}
 	}
 
+	switch sqe.Opcode() {
+	case iouring_syscall.IORING_OP_SPLICE, iouring_syscall.IORING_OP_TEE:
+		if index, ok := iour.fileRegister.GetFileIndex(sqe.SpliceFdIn()); ok {
+			sqe.SetSpliceFdIn(int32(index))
+			sqe.SetOpFlags(sqe.OpFlags() | iouring_syscall.IOSQE_SPLICE_F_FD_IN_FIXED)
+		}
+	case iouring_syscall.IORING_OP_READ_FIXED, iouring_syscall.IORING_OP_WRITE_FIXED:
+		if !iour.isRegisteredBuffer(int(sqe.BufIndex()), userData.request.b0) {
+			return nil, ErrUnregisteredBuffer
+		}
+	}
+
 	if iour.async {
 		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_ASYNC)
 	}
@@ -194,6 +400,9 @@ func (iour *IOURing) SubmitRequest(request PrepRequest, ch chan<- Result) (Reque
 	if iour.IsClosed() {
 		return nil, ErrIOURingClosed
 	}
+	if iour.disabled {
+		return nil, ErrIOURingDisabled
+	}
 
 	sqe := iour.getSQEntry()
 	userData, err := iour.doRequest(sqe, request, ch)
@@ -216,56 +425,195 @@ func (iour *IOURing) SubmitRequest(request PrepRequest, ch chan<- Result) (Reque
 	return userData.request, nil
 }
 
-// SubmitRequests by Request functions and io results are notified via channel
-func (iour *IOURing) SubmitRequests(requests []PrepRequest, ch chan<- Result) (RequestSet, error) {
-	// TODO(iceber): no length limit
-	if len(requests) > int(*iour.sq.entries) {
-		return nil, errors.New("too many requests")
+// SubmitRequestWithContext submit request like SubmitRequest,
+// and the request will be canceled if ctx is done before the request is completed
+func (iour *IOURing) SubmitRequestWithContext(ctx context.Context, prepRequest PrepRequest, ch chan<- Result) (Request, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	req, err := iour.SubmitRequest(prepRequest, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Done() != nil {
+		id := req.(*request).id
+		go func() {
+			select {
+			case <-req.Done():
+			case <-ctx.Done():
+				_, _ = iour.submitCancel(id)
+			}
+		}()
+	}
+	return req, nil
+}
+
+// SubmitRequestSync submit request and wait for the request to be completed,
+// the returned request is also the result of the request.
+// With WithExternalEventLoop, it must not be called by the goroutine of the event loop
+func (iour *IOURing) SubmitRequestSync(prepRequest PrepRequest) (Request, error) {
+	req, err := iour.SubmitRequest(prepRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	<-req.Done()
+	return req, nil
+}
+
+// SubmitRequestSyncWithTimeout submit request like SubmitRequestSync,
+// the request is canceled and completed with ErrRequestCanceled if it's not completed before the timeout
+func (iour *IOURing) SubmitRequestSyncWithTimeout(prepRequest PrepRequest, timeout time.Duration) (Request, error) {
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{prepRequest, LinkTimeout(timeout)}, nil)
+	if err != nil {
+		return nil, err
 	}
 
+	req := requests.Requests()[0]
+	<-req.Done()
+	return req, nil
+}
+
+// SubmitRequests by Request functions and io results are notified via channel.
+// When there are more requests than free submission queue entries, the requests
+// are submitted in chunks while holding the submit lock.
+// If a request fails to be prepared or submitted, the requests that have not been submitted
+// are rolled back, and the requests of the submitted chunks are canceled. The submitted
+// requests may have been executed and their results are still notified via channel,
+// so the RequestSet of them is returned with the error if any chunk has been submitted
+func (iour *IOURing) SubmitRequests(requests []PrepRequest, ch chan<- Result) (RequestSet, error) {
 	iour.submitLock.Lock()
 	defer iour.submitLock.Unlock()
 
 	if iour.IsClosed() {
 		return nil, ErrIOURingClosed
 	}
+	if iour.disabled {
+		return nil, ErrIOURingDisabled
+	}
+
+	// the request set must be attached to the requests before they are submitted
+	rset := &requestSet{
+		requests: make([]Request, len(requests)),
+		total:    int32(len(requests)),
+		done:     make(chan struct{}),
+	}
 
-	var sqeN uint32
+	// submitted is the number of the submitted requests,
+	// linkStart is the index of the first request of the incomplete link chain
+	var submitted, linkStart int
 	userDatas := make([]*UserData, 0, len(requests))
-	for _, request := range requests {
-		sqe := iour.getSQEntry()
-		sqeN++
+	fail := func(err error) (RequestSet, error) {
+		iour.cancelUserDatas(userDatas[:submitted])
+		if submitted == 0 {
+			return nil, err
+		}
+		rset.truncate(submitted)
+		return rset, err
+	}
+	for i := range requests {
+		sqe := iour.sq.getSQEntry()
+		if sqe == nil {
+			chain := len(userDatas) - linkStart
+			if chain >= int(*iour.sq.entries) {
+				iour.sq.fallback(uint32(len(userDatas) - submitted))
+				return fail(errors.New("link chain is too long"))
+			}
+
+			// a link chain cannot be split into two submissions,
+			// the entries of the incomplete link chain are kept in the submission queue
+			// without being published, and submitted with the rest of the chain
+			iour.sq.unflushed = uint32(chain)
+			if linkStart > submitted {
+				// submission queue is full, submit the prepared chunk to make room
+				n, err := iour.submitUserDatas(userDatas[submitted:linkStart])
+				if err != nil {
+					// the incomplete link chain is dropped with the unsubmitted entries
+					submitted += n
+					return fail(err)
+				}
+				submitted = linkStart
+			}
+			sqe = iour.getSQEntry()
+			iour.sq.unflushed = 0
+		}
 
-		userData, err := iour.doRequest(sqe, request, ch)
+		userData, err := iour.doRequest(sqe, requests[i], ch)
 		if err != nil {
-			iour.sq.fallback(sqeN)
-			return nil, err
+			iour.sq.fallback(uint32(len(userDatas) - submitted + 1))
+			return fail(err)
 		}
+		userData.request.set = rset
+		rset.requests[i] = userData.request
 		userDatas = append(userDatas, userData)
+
+		if sqe.Flags()&(iouring_syscall.IOSQE_FLAGS_IO_LINK|iouring_syscall.IOSQE_FLAGS_IO_HARDLINK) == 0 {
+			linkStart = len(userDatas)
+		}
 	}
 
-	// must be located before the lock operation to
-	// avoid the compiler's adjustment of the code order.
-	// issue: https://github.com/Iceber/iouring-go/issues/8
-	rset := newRequestSet(userDatas)
+	n, err := iour.submitUserDatas(userDatas[submitted:])
+	if err != nil {
+		submitted += n
+		return fail(err)
+	}
+	return rset, nil
+}
 
+// submitUserDatas submit the prepared submission queue entries of userDatas,
+// and return the number of the submitted userDatas, the rest are dropped on error.
+// must be called with the submit lock held
+func (iour *IOURing) submitUserDatas(userDatas []*UserData) (int, error) {
 	iour.userDataLock.Lock()
 	for _, data := range userDatas {
 		iour.userDatas[data.id] = data
 	}
 	iour.userDataLock.Unlock()
 
-	if _, err := iour.submit(); err != nil {
+	submitted, err := iour.submit()
+	if err != nil {
+		if submitted > len(userDatas) {
+			submitted = len(userDatas)
+		}
+
 		iour.userDataLock.Lock()
-		for _, data := range userDatas {
+		for _, data := range userDatas[submitted:] {
 			delete(iour.userDatas, data.id)
 		}
 		iour.userDataLock.Unlock()
 
-		return nil, err
+		return submitted, err
 	}
+	return len(userDatas), nil
+}
 
-	return rset, nil
+// cancelUserDatas cancel the submitted requests which cannot be rolled back by fallback,
+// must be called with the submit lock held
+func (iour *IOURing) cancelUserDatas(userDatas []*UserData) {
+	if len(userDatas) == 0 {
+		return
+	}
+
+	cancels := make([]*UserData, 0, len(userDatas))
+	for _, data := range userDatas {
+		sqe := iour.sq.getSQEntry()
+		if sqe == nil {
+			_, _ = iour.submitUserDatas(cancels)
+			cancels = cancels[:0]
+
+			sqe = iour.getSQEntry()
+		}
+
+		cancel, err := iour.doRequest(sqe, cancelRequest(data.id), nil)
+		if err != nil {
+			iour.sq.fallback(1)
+			continue
+		}
+		cancels = append(cancels, cancel)
+	}
+	_, _ = iour.submitUserDatas(cancels)
 }
 
 func (iour *IOURing) needEnter(flags *uint32) bool {
@@ -273,26 +621,63 @@ func (iour *IOURing) needEnter(flags *uint32) bool {
 		return true
 	}
 
+	// IORING_SQ_NEED_WAKEUP is set by the kernel when the poll thread has gone to sleep
+	// after the idle time, it must be woken up by io_uring_enter
 	if iour.sq.needWakeup() {
-		*flags |= iouring_syscall.IORING_SQ_NEED_WAKEUP
+		*flags |= iouring_syscall.IORING_ENTER_FLAGS_SQ_WAKEUP
 		return true
 	}
 	return false
 }
 
+// submit submit the pending submission queue entries.
+// Without IORING_SETUP_SUBMIT_ALL, the kernel stops submitting at the entry that
+// fails to be submitted and leaves the following entries in the submission queue,
+// they are submitted again until all the entries are consumed.
+// io_uring_enter is retried on the temporary errors, such as EBUSY when the completion
+// queue overflows. On other errors, the entries which are not consumed by the kernel
+// are dropped from the submission queue, and the number of consumed entries is returned
 func (iour *IOURing) submit() (submitted int, err error) {
-	submitted = iour.sq.flush()
+	pending := iour.sq.flush()
 
 	var flags uint32
-	if !iour.needEnter(&flags) || submitted == 0 {
-		return
+	if !iour.needEnter(&flags) || pending == 0 {
+		return pending, nil
 	}
 
 	if (iour.Flags & iouring_syscall.IORING_SETUP_IOPOLL) != 0 {
 		flags |= iouring_syscall.IORING_ENTER_FLAGS_GETEVENTS
 	}
 
-	submitted, err = iouring_syscall.IOURingEnter(iour.fd, uint32(submitted), 0, flags, nil)
+	for submitted < pending {
+		var n int
+		n, err = iouring_syscall.IOURingEnter(iour.fd, uint32(pending-submitted), 0, flags, nil)
+		if err != nil && !isTemporaryEnterErr(err) {
+			iour.logf("submit %d of %d entries, error: %v", submitted, pending, err)
+			if (iour.Flags & iouring_syscall.IORING_SETUP_SQPOLL) != 0 {
+				// the published entries may be consumed by the poll thread at any time
+				iour.sq.drop(0)
+			} else {
+				iour.sq.drop(uint32(pending - submitted))
+			}
+			return
+		}
+		if err != nil || n == 0 {
+			// the completion events are reaped by the run goroutine concurrently
+			runtime.Gosched()
+			continue
+		}
+		submitted += n
+	}
+	err = nil
+
+	if (iour.Flags & iouring_syscall.IORING_SETUP_IOPOLL) != 0 {
+		// wake up the run goroutine to poll the completions
+		select {
+		case iour.cqeSign <- struct{}{}:
+		default:
+		}
+	}
 	return
 }
 
@@ -314,13 +699,17 @@ func (iour *IOURing) submitAndWait(waitCount uint32) (submitted int, err error)
 }
 */
 
-func (iour *IOURing) getCQEvent(wait bool) (cqe iouring_syscall.CompletionQueueEvent, err error) {
+// getCQEvents fill cqes with the available completion events and advance the head once,
+// it waits for the completion events if there is none and wait is true
+func (iour *IOURing) getCQEvents(cqes []iouring_syscall.CompletionQueueEvent, wait bool) (n int, err error) {
 	var tryPeeks int
 	for {
-		if cqe = iour.cq.peek(); cqe != nil {
-			// Copy CQE.
-			cqe = cqe.Clone()
-			iour.cq.advance(1)
+		if n = iour.cq.peekBatch(cqes); n != 0 {
+			// copy the completion events, the entries are reused by the kernel after advancing
+			for i := 0; i < n; i++ {
+				cqes[i] = cqes[i].Clone()
+			}
+			iour.cq.advance(uint32(n))
 			return
 		}
 
@@ -330,6 +719,9 @@ func (iour *IOURing) getCQEvent(wait bool) (cqe iouring_syscall.CompletionQueueE
 		}
 
 		if iour.sq.cqOverflow() {
+			atomic.AddUint64(&iour.cqOverflows, 1)
+			iour.logf("completion queue overflow, flush the overflow list")
+
 			_, err = iouring_syscall.IOURingEnter(iour.fd, 0, 0, iouring_syscall.IORING_ENTER_FLAGS_GETEVENTS, nil)
 			if err != nil {
 				return
@@ -337,6 +729,22 @@ func (iour *IOURing) getCQEvent(wait bool) (cqe iouring_syscall.CompletionQueueE
 			continue
 		}
 
+		if (iour.Flags&iouring_syscall.IORING_SETUP_IOPOLL) != 0 && iour.hasInflight() {
+			// IOPOLL ring doesn't post the completions by interrupts,
+			// they must be polled by io_uring_enter with GETEVENTS
+			_, err = iouring_syscall.IOURingEnter(iour.fd, 0, 1, iouring_syscall.IORING_ENTER_FLAGS_GETEVENTS, nil)
+			if err != nil && !isTemporaryEnterErr(err) {
+				return
+			}
+
+			select {
+			case <-iour.stop:
+				return 0, ErrIOURingClosed
+			default:
+			}
+			continue
+		}
+
 		if tryPeeks++; tryPeeks < 3 {
 			runtime.Gosched()
 			continue
@@ -344,49 +752,195 @@ func (iour *IOURing) getCQEvent(wait bool) (cqe iouring_syscall.CompletionQueueE
 
 		select {
 		case <-iour.cqeSign:
-		case <-iour.closer:
-			return nil, ErrIOURingClosed
+		case <-iour.stop:
+			return 0, ErrIOURingClosed
 		}
 	}
 }
 
+func (iour *IOURing) hasInflight() bool {
+	iour.userDataLock.RLock()
+	defer iour.userDataLock.RUnlock()
+	return len(iour.userDatas) != 0
+}
+
 func (iour *IOURing) run() {
+	defer func() {
+		close(iour.errs)
+		close(iour.closed)
+	}()
+
+	cqes := make([]iouring_syscall.CompletionQueueEvent, *iour.cq.entries)
 	for {
-		cqe, err := iour.getCQEvent(true)
-		if cqe == nil || err != nil {
-			if err == ErrIOURingClosed {
-				close(iour.closed)
-				return
+		n, err := iour.getCQEvents(cqes, true)
+		if err == ErrIOURingClosed {
+			return
+		}
+		if err != nil {
+			iour.logf("runComplete error: %v", err)
+			if isTemporaryEnterErr(err) {
+				continue
 			}
-			log.Println("runComplete error: ", err)
+
+			// the completion events can't be reaped anymore,
+			// the in-flight requests are terminated by Close
+			iour.errs <- err
+			<-iour.closer
+			return
+		}
+		if n == 0 {
 			continue
 		}
 
-		// log.Println("cqe user data", (cqe.UserData))
+		for i := 0; i < n; i++ {
+			iour.complete(cqes[i])
+			cqes[i] = nil
+		}
+		iour.notifyCQEvents(n)
+	}
+}
 
-		iour.userDataLock.Lock()
-		userData := iour.userDatas[cqe.UserData()]
-		if userData == nil {
-			iour.userDataLock.Unlock()
-			log.Println("runComplete: notfound user data ", uintptr(cqe.UserData()))
-			continue
+// Err returns a channel that receives the fatal error of the completion loop,
+// the requests are not completed anymore after the error and the IOURing
+// should be closed. The channel is closed after the IOURing is closed
+func (iour *IOURing) Err() <-chan error {
+	return iour.errs
+}
+
+func isTemporaryEnterErr(err error) bool {
+	return errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY)
+}
+
+func (iour *IOURing) notifyCQEvents(n int) {
+	iour.cqEventsLock.Lock()
+	iour.cqEvents += uint64(n)
+	if iour.cqEventsSign != nil {
+		close(iour.cqEventsSign)
+		iour.cqEventsSign = nil
+	}
+	iour.cqEventsLock.Unlock()
+}
+
+// WaitCQEvents wait until n completion events are reaped after the call,
+// ErrWaitTimeout is returned if they are not reaped before the timeout
+func (iour *IOURing) WaitCQEvents(n uint32, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	iour.cqEventsLock.Lock()
+	target := iour.cqEvents + uint64(n)
+	for iour.cqEvents < target {
+		if iour.cqEventsSign == nil {
+			iour.cqEventsSign = make(chan struct{})
 		}
-		delete(iour.userDatas, cqe.UserData())
+		sign := iour.cqEventsSign
+		iour.cqEventsLock.Unlock()
+
+		select {
+		case <-sign:
+		case <-timer.C:
+			return ErrWaitTimeout
+		case <-iour.closer:
+			return ErrIOURingClosed
+		}
+		iour.cqEventsLock.Lock()
+	}
+	iour.cqEventsLock.Unlock()
+	return nil
+}
+
+func (iour *IOURing) complete(cqe iouring_syscall.CompletionQueueEvent) {
+	if iour.debug {
+		iour.logf("cqe user data: %d, result: %d, flags: %d", cqe.UserData(), cqe.Result(), cqe.Flags())
+	}
+
+	// the multishot request will post more completion events,
+	// user data must be kept until the final one
+	more := cqe.Flags()&iouring_syscall.IORING_CQE_F_MORE != 0
+
+	iour.userDataLock.Lock()
+	userData := iour.userDatas[cqe.UserData()]
+	if userData == nil {
 		iour.userDataLock.Unlock()
+		iour.logf("runComplete: notfound user data %d", cqe.UserData())
+		return
+	}
+	if !more {
+		delete(iour.userDatas, cqe.UserData())
+	}
+	iour.userDataLock.Unlock()
+
+	if userData.bufSelect && cqe.Flags()&iouring_syscall.IORING_CQE_F_BUFFER != 0 {
+		if ring, ok := iour.bufRings.Load(userData.bufGroup); ok {
+			ring.(*BufRing).selected(uint16(cqe.Flags() >> iouring_syscall.IORING_CQE_BUFFER_SHIFT))
+		}
+	}
+
+	request := userData.request
+	if more {
+		// keep the result on the request, it's used by the final event without a result
+		request.res = cqe.Result()
+		request = request.clone()
+	}
+	if !more && cqe.Result() == -int32(syscall.ECANCELED) && iour.IsClosed() {
+		// the request is canceled by Close
+		request.terminate(ErrIOURingClosed)
+	} else {
+		request.complate(cqe)
+	}
+	if userData.completed != nil && !more {
+		userData.completed()
+	}
+
+	// ignore link timeout
+	if userData.opcode == iouring_syscall.IORING_OP_LINK_TIMEOUT {
+		return
+	}
+
+	if userData.resulter != nil {
+		iour.notifyResult(userData.resulter, request)
+	}
+}
 
-		userData.request.complate(cqe)
+// notifyResult send the result to ch, once Close is called the result is sent
+// without blocking, Close may be called by the goroutine receiving from ch
+func (iour *IOURing) notifyResult(ch chan<- Result, result Result) {
+	select {
+	case ch <- result:
+	case <-iour.closer:
+		go func() { ch <- result }()
+	}
+}
+
+// terminateRequests fail the in-flight requests with ErrIOURingClosed,
+// must be called after the run goroutine has exited
+func (iour *IOURing) terminateRequests() {
+	iour.userDataLock.Lock()
+	userDatas := make([]*UserData, 0, len(iour.userDatas))
+	for id, data := range iour.userDatas {
+		userDatas = append(userDatas, data)
+		delete(iour.userDatas, id)
+	}
+	iour.userDataLock.Unlock()
+
+	for _, data := range userDatas {
+		data.request.terminate(ErrIOURingClosed)
 
 		// ignore link timeout
-		if userData.opcode == iouring_syscall.IORING_OP_LINK_TIMEOUT {
+		if data.opcode == iouring_syscall.IORING_OP_LINK_TIMEOUT {
 			continue
 		}
 
-		if userData.resulter != nil {
-			userData.resulter <- userData.request
+		if data.resulter != nil {
+			iour.notifyResult(data.resulter, data.request)
 		}
 	}
 }
 
+func (iour *IOURing) logf(format string, v ...interface{}) {
+	iour.logger.Printf(format, v...)
+}
+
 // Result submit cancel request
 func (iour *IOURing) submitCancel(id uint64) (Request, error) {
 	if iour == nil {
@@ -396,6 +950,166 @@ func (iour *IOURing) submitCancel(id uint64) (Request, error) {
 	return iour.SubmitRequest(cancelRequest(id), nil)
 }
 
+// CancelFd cancel all in-flight requests on fd, the canceled requests are completed with ErrRequestCanceled,
+// and the result returns the number of canceled requests by ReturnInt.
+// IORING_ASYNC_CANCEL_FD is available since 5.19, on the older kernels the in-flight
+// requests on fd are canceled one by one, and the result is completed after all of them
+func (iour *IOURing) CancelFd(fd int, ch chan<- Result) (Request, error) {
+	if !iour.cancelFlagsSupported() {
+		return iour.cancelEach(fd, func(data *UserData) bool { return data.request.fd == fd }, ch)
+	}
+
+	return iour.SubmitRequest(func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		sqe.PrepOperation(iouring_syscall.IORING_OP_ASYNC_CANCEL, int32(fd), 0, 0, 0)
+		sqe.SetOpFlags(iouring_syscall.IORING_ASYNC_CANCEL_FD | iouring_syscall.IORING_ASYNC_CANCEL_ALL)
+	}, ch)
+}
+
+// CancelByFd cancel all in-flight requests on fd like CancelFd,
+// the number of canceled requests is returned by ReturnInt of the result notified via ch
+func (iour *IOURing) CancelByFd(fd int, ch chan<- Result) error {
+	_, err := iour.CancelFd(fd, ch)
+	return err
+}
+
+// CancelAll cancel all in-flight requests, the canceled requests are completed with ErrRequestCanceled,
+// and the result returns the number of canceled requests by ReturnInt.
+// IORING_ASYNC_CANCEL_ANY is available since 5.19, on the older kernels the in-flight
+// requests are canceled one by one, and the result is completed after all of them
+func (iour *IOURing) CancelAll(ch chan<- Result) (Request, error) {
+	if !iour.cancelFlagsSupported() {
+		return iour.cancelEach(-1, func(*UserData) bool { return true }, ch)
+	}
+
+	return iour.SubmitRequest(func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		sqe.PrepOperation(iouring_syscall.IORING_OP_ASYNC_CANCEL, -1, 0, 0, 0)
+		sqe.SetOpFlags(iouring_syscall.IORING_ASYNC_CANCEL_ANY | iouring_syscall.IORING_ASYNC_CANCEL_ALL)
+	}, ch)
+}
+
+const (
+	probeUnknown int32 = iota
+	probeSupported
+	probeUnsupported
+	probing
+)
+
+// cancelFlagsSupported reports whether the IORING_ASYNC_CANCEL_* flags are supported,
+// the kernels before 5.19 fail the cancel request with the flags by EINVAL
+func (iour *IOURing) cancelFlagsSupported() bool {
+	switch atomic.LoadInt32(&iour.cancelFlags) {
+	case probeSupported:
+		return true
+	case probeUnsupported:
+		return false
+	}
+
+	if iour.externalLoop {
+		// the probe is completed by ReapCompletions of the event loop which may be the caller,
+		// the requests are canceled one by one until the probe is completed
+		if atomic.CompareAndSwapInt32(&iour.cancelFlags, probeUnknown, probing) {
+			if _, err := iour.SubmitRequest(iour.cancelFlagsProbe(), nil); err != nil {
+				atomic.StoreInt32(&iour.cancelFlags, probeUnknown)
+			}
+		}
+		return false
+	}
+
+	probe, err := iour.SubmitRequestSync(iour.cancelFlagsProbe())
+	if err != nil {
+		return false
+	}
+	return cancelFlagsOf(probe.(*request).res, probe.Err() == ErrIOURingClosed) == probeSupported
+}
+
+// cancelFlagsProbe cancel with IORING_ASYNC_CANCEL_ALL, the request ids start at 1,
+// nothing is canceled by the probe. The result is cached when the probe is completed
+func (iour *IOURing) cancelFlagsProbe() PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		userData.completed = func() {
+			res := userData.request.res
+			closed := res == -int32(syscall.ECANCELED) && iour.IsClosed()
+			atomic.StoreInt32(&iour.cancelFlags, cancelFlagsOf(res, closed))
+		}
+		sqe.PrepOperation(iouring_syscall.IORING_OP_ASYNC_CANCEL, -1, 0, 0, 0)
+		sqe.SetOpFlags(iouring_syscall.IORING_ASYNC_CANCEL_ALL)
+	}
+}
+
+// cancelFlagsOf returns the cancel flags probed by the result of the probe,
+// closed is true if the probe is canceled by Close
+func cancelFlagsOf(res int32, closed bool) int32 {
+	if closed {
+		// the probe is not completed, probe again next time
+		return probeUnknown
+	}
+	if res == -int32(syscall.EINVAL) {
+		return probeUnsupported
+	}
+	return probeSupported
+}
+
+// cancelEach cancel the in-flight requests matched by match one by one,
+// the returned request is completed after all the cancel requests are completed,
+// and the result is the number of canceled requests
+func (iour *IOURing) cancelEach(fd int, match func(*UserData) bool, ch chan<- Result) (Request, error) {
+	if iour.IsClosed() {
+		return nil, ErrIOURingClosed
+	}
+
+	var prepRequests []PrepRequest
+	iour.userDataLock.RLock()
+	for _, data := range iour.userDatas {
+		// the link timeouts are canceled with the linked requests
+		if data.opcode == iouring_syscall.IORING_OP_ASYNC_CANCEL ||
+			data.opcode == iouring_syscall.IORING_OP_LINK_TIMEOUT || !match(data) {
+			continue
+		}
+		prepRequests = append(prepRequests, cancelRequest(data.id))
+	}
+	iour.userDataLock.RUnlock()
+
+	result := &request{
+		iour:     iour,
+		opcode:   iouring_syscall.IORING_OP_ASYNC_CANCEL,
+		fd:       fd,
+		resolver: fdResolver,
+		done:     make(chan struct{}),
+	}
+	complete := func(canceled int32) {
+		result.res = canceled
+		close(result.done)
+		if ch != nil {
+			ch <- result
+		}
+	}
+
+	if len(prepRequests) == 0 {
+		go complete(0)
+		return result, nil
+	}
+
+	cancels, err := iour.SubmitRequests(prepRequests, nil)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-cancels.Done()
+
+		var canceled int32
+		for _, cancel := range cancels.Requests() {
+			if cancel.Err() == nil {
+				canceled++
+			}
+		}
+		complete(canceled)
+	}()
+	return result, nil
+}
+
 func cancelRequest(id uint64) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = cancelResolver
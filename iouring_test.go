@@ -1,18 +1,28 @@
 package iouring
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
 
-func testSubmitRequests(t *testing.T, nreqs uint) {
+func testSubmitRequests(t *testing.T, entries, nreqs uint) {
 	f, err := os.Open("/dev/zero") // For read access.
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer f.Close()
-	iour, err := New(nreqs)
+	iour, err := New(entries)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -44,6 +54,1087 @@ func testSubmitRequests(t *testing.T, nreqs uint) {
 func TestSubmitRequests(t *testing.T) {
 	for i := uint(0); i < 8; i++ {
 		nreqs := uint(1 << i)
-		t.Run(fmt.Sprintf("%d", nreqs), func(t *testing.T) { testSubmitRequests(t, nreqs) })
+		t.Run(fmt.Sprintf("%d", nreqs), func(t *testing.T) { testSubmitRequests(t, nreqs, nreqs) })
+	}
+
+	// more requests than the submission queue size are submitted in chunks
+	t.Run("4x", func(t *testing.T) { testSubmitRequests(t, 8, 32) })
+}
+
+func TestSubmitRequestsLinkChain(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	// the link chain at the boundary of the first chunk must be submitted in the next chunk
+	preqs := []PrepRequest{Nop(), Nop(), Nop()}
+	preqs = append(preqs, Timeout(time.Hour).WithTimeout(10*time.Millisecond)...)
+	preqs = append(preqs, Nop())
+
+	requests, err := iour.SubmitRequests(preqs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-requests.Requests()[3].Done():
+	case <-time.After(time.Second):
+		t.Fatal("link timeout is not linked with the request")
+	}
+	if err := requests.Requests()[3].Err(); err != ErrRequestCanceled {
+		t.Fatalf("request with link timeout: %v, want %v", err, ErrRequestCanceled)
+	}
+
+	linkNop := func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		Nop()(sqe, userData)
+		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_IO_LINK)
+	}
+	preqs = []PrepRequest{Nop(), linkNop, linkNop, linkNop, linkNop, Nop()}
+	if _, err := iour.SubmitRequests(preqs, nil); err == nil {
+		t.Fatal("submit a link chain longer than the submission queue")
+	}
+}
+
+func TestSubmitRequestsLinkChainPreparedOnce(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	var prepared int
+	countNop := func(link bool) PrepRequest {
+		return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+			prepared++
+			Nop()(sqe, userData)
+			if link {
+				sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_IO_LINK)
+			}
+		}
+	}
+
+	// the link chain at the boundary of the first chunk is not prepared again
+	preqs := []PrepRequest{countNop(false), countNop(false), countNop(true), countNop(true), countNop(false), countNop(false)}
+	requests, err := iour.SubmitRequests(preqs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+	if prepared != len(preqs) {
+		t.Fatalf("requests are prepared %d times, want %d", prepared, len(preqs))
+	}
+}
+
+func TestSubmitRequestsRollback(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	preqs := make([]PrepRequest, 16)
+	for i := range preqs {
+		preqs[i] = Timeout(time.Hour)
+	}
+	// ReadFixed without registered buffers fails to be prepared
+	preqs[10] = ReadFixed(0, make([]byte, 1), 0, 0)
+
+	ch := make(chan Result, len(preqs))
+	requests, err := iour.SubmitRequests(preqs, ch)
+	if err != ErrUnregisteredBuffer {
+		t.Fatalf("submit requests: %v, want %v", err, ErrUnregisteredBuffer)
+	}
+	// the request set of the submitted chunks is returned with the error
+	if requests == nil || requests.Len() != 8 {
+		t.Fatalf("request set of the submitted chunks is %v, want 8 requests", requests)
+	}
+	select {
+	case <-requests.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request set of the submitted chunks is not done")
+	}
+
+	// requests of the submitted chunks are canceled
+	for i := 0; i < 8; i++ {
+		select {
+		case result := <-ch:
+			if result.Err() != ErrRequestCanceled {
+				t.Fatalf("submitted request: %v, want %v", result.Err(), ErrRequestCanceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d submitted requests are not canceled", 8-i)
+		}
+	}
+
+	request, err := iour.SubmitRequest(Nop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if request.Err() != nil {
+		t.Fatal(request.Err())
+	}
+}
+
+func TestClose(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Result, 1)
+	request, err := iour.SubmitRequest(Timeout(time.Hour), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := iour.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.Close(); err != nil {
+		t.Fatalf("close twice: %v", err)
+	}
+
+	<-request.Done()
+	if result := <-ch; result.Err() != ErrIOURingClosed {
+		t.Fatalf("pending request error is %v, want %v", result.Err(), ErrIOURingClosed)
+	}
+
+	if _, err := iour.SubmitRequest(Nop(), nil); err != ErrIOURingClosed {
+		t.Fatalf("submit after close error is %v, want %v", err, ErrIOURingClosed)
+	}
+}
+
+func TestCloseWithUnreadChannel(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// the results are received by the goroutine calling Close
+	ch := make(chan Result)
+	if _, err := iour.SubmitRequests([]PrepRequest{
+		Timeout(time.Hour),
+		Read(int(r.Fd()), make([]byte, 16)),
+	}, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- iour.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked by the unread channel")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-ch:
+			if result.Err() != ErrIOURingClosed {
+				t.Fatalf("pending request error is %v, want %v", result.Err(), ErrIOURingClosed)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("result of the pending request is not notified")
+		}
+	}
+
+	// the read request is canceled in the kernel, it doesn't consume the data of the pipe
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("data of the pipe is consumed by the canceled read: %v", err)
+	}
+}
+
+func TestCloseWithUndrainedChannel(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the result is notified while the ring is open, nobody receives from ch
+	ch := make(chan Result)
+	if _, err := iour.SubmitRequest(Nop(), ch); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- iour.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked by the undrained channel")
+	}
+}
+
+func TestSubmitRequestWithContext(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan Result, 1)
+	request, err := iour.SubmitRequestWithContext(ctx, Timeout(time.Hour), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case result := <-ch:
+		if result != request {
+			t.Fatal("result is not the submitted request")
+		}
+		if result.Err() != ErrRequestCanceled {
+			t.Fatalf("request with canceled context: %v, want %v", result.Err(), ErrRequestCanceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request is not canceled with the context")
+	}
+
+	if _, err := iour.SubmitRequestWithContext(ctx, Nop(), nil); err != context.Canceled {
+		t.Fatalf("submit with canceled context: %v, want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	request, err = iour.SubmitRequestWithContext(ctx, Nop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if request.Err() != nil {
+		t.Fatal(request.Err())
+	}
+}
+
+func TestSubmitRequestSync(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	request, err := iour.SubmitRequestSync(Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Err() != nil {
+		t.Fatal(request.Err())
+	}
+
+	request, err = iour.SubmitRequestSyncWithTimeout(Nop(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Err() != nil {
+		t.Fatal(request.Err())
+	}
+
+	request, err = iour.SubmitRequestSyncWithTimeout(Timeout(time.Hour), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Err() != ErrRequestCanceled {
+		t.Fatalf("request with timeout: %v, want %v", request.Err(), ErrRequestCanceled)
+	}
+}
+
+func TestReturnValue(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	request, err := iour.SubmitRequestSync(Read(-1, make([]byte, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := request.ReturnValue(); res != -int(syscall.EBADF) || err != syscall.EBADF {
+		t.Fatalf("ReturnValue returns %d, %v, want %d, %v", res, err, -int(syscall.EBADF), syscall.EBADF)
+	}
+
+	// the ECANCELED of the canceled request is not translated to ErrRequestCanceled
+	request, err = iour.SubmitRequestSyncWithTimeout(Timeout(time.Hour), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request.ReturnValue(); err != syscall.ECANCELED {
+		t.Fatalf("ReturnValue of the canceled request returns %v, want %v", err, syscall.ECANCELED)
+	}
+
+	request, err = iour.SubmitRequestSync(Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := request.ReturnValue(); res != 0 || err != nil {
+		t.Fatalf("ReturnValue of nop returns %d, %v", res, err)
+	}
+}
+
+func TestCancelFdAndCancelAll(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	requests, err := iour.SubmitRequests([]PrepRequest{
+		Read(int(r.Fd()), make([]byte, 1)),
+		Read(int(r.Fd()), make([]byte, 1)),
+		Timeout(time.Hour),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Result, 1)
+	if err := iour.CancelByFd(int(r.Fd()), ch); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := (<-ch).ReturnInt(); err != nil || n != 2 {
+		t.Fatalf("cancel the requests on fd: %d, %v, want 2 canceled", n, err)
+	}
+	for _, request := range requests.Requests()[:2] {
+		<-request.Done()
+		if err := request.Err(); err != ErrRequestCanceled {
+			t.Fatalf("request on the canceled fd: %v, want %v", err, ErrRequestCanceled)
+		}
+	}
+
+	cancel, err := iour.CancelAll(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cancel.Done()
+	if n, err := cancel.ReturnInt(); err != nil || n != 1 {
+		t.Fatalf("cancel all requests: %d, %v, want 1 canceled", n, err)
+	}
+	<-requests.Done()
+	if err := requests.Requests()[2].Err(); err != ErrRequestCanceled {
+		t.Fatalf("timeout request: %v, want %v", err, ErrRequestCanceled)
+	}
+}
+
+// disableCancelFlags force the fallback of the kernels before 5.19
+func disableCancelFlags(iour *IOURing) {
+	atomic.StoreInt32(&iour.cancelFlags, probeUnsupported)
+}
+
+func TestCancelFdFallback(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	disableCancelFlags(iour)
+
+	r1, w1, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close()
+	defer w1.Close()
+
+	r2, w2, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	defer w2.Close()
+
+	requests, err := iour.SubmitRequests([]PrepRequest{
+		Read(int(r1.Fd()), make([]byte, 1)),
+		Read(int(r1.Fd()), make([]byte, 1)),
+		Read(int(r2.Fd()), make([]byte, 1)),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel, err := iour.CancelFd(int(r1.Fd()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cancel.Done()
+	if n, err := cancel.ReturnInt(); err != nil || n != 2 {
+		t.Fatalf("cancel the requests on fd: %d, %v, want 2 canceled", n, err)
+	}
+	if fd := cancel.Fd(); fd != int(r1.Fd()) {
+		t.Fatalf("fd of the cancel request is %d, want %d", fd, r1.Fd())
+	}
+	for _, request := range requests.Requests()[:2] {
+		<-request.Done()
+		if err := request.Err(); err != ErrRequestCanceled {
+			t.Fatalf("request on the canceled fd: %v, want %v", err, ErrRequestCanceled)
+		}
+	}
+
+	if _, err := w2.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if n, err := requests.Requests()[2].ReturnInt(); err != nil || n != 1 {
+		t.Fatalf("request on the other fd: %d, %v, want 1 byte read", n, err)
+	}
+}
+
+func TestCancelAllFallback(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	disableCancelFlags(iour)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	requests, err := iour.SubmitRequests([]PrepRequest{
+		Read(int(r.Fd()), make([]byte, 1)),
+		Read(int(r.Fd()), make([]byte, 1)),
+		Timeout(time.Hour),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Result, 1)
+	cancel, err := iour.CancelAll(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := <-ch; result != cancel {
+		t.Fatal("result of the channel is not the returned request")
+	}
+	if n, err := cancel.ReturnInt(); err != nil || n != 3 {
+		t.Fatalf("cancel all requests: %d, %v, want 3 canceled", n, err)
+	}
+	<-requests.Done()
+	for _, request := range requests.Requests() {
+		if err := request.Err(); err != ErrRequestCanceled {
+			t.Fatalf("canceled request: %v, want %v", err, ErrRequestCanceled)
+		}
+	}
+
+	cancel, err = iour.CancelAll(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cancel.Done()
+	if n, err := cancel.ReturnInt(); err != nil || n != 0 {
+		t.Fatalf("cancel without in-flight requests: %d, %v, want 0 canceled", n, err)
+	}
+}
+
+func TestConcurrentSubmitRequest(t *testing.T) {
+	iour, err := New(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	const goroutines, nreqs = 16, 256
+	ch := make(chan Result, goroutines*nreqs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < nreqs; j++ {
+				if _, err := iour.SubmitRequest(Nop(), ch); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < goroutines*nreqs; i++ {
+		if err := (<-ch).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentSubmitRequests(t *testing.T) {
+	iour, err := New(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	const goroutines, batchs, batchSize = 16, 64, 8
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			preqs := make([]PrepRequest, batchSize)
+			for j := range preqs {
+				preqs[j] = Nop()
+			}
+			for j := 0; j < batchs; j++ {
+				requests, err := iour.SubmitRequests(preqs, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				<-requests.Done()
+				if errResults := requests.ErrResults(); errResults != nil {
+					t.Error(errResults[0].Err())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSQPollWakeup(t *testing.T) {
+	iour, err := New(8, WithSQPoll(), WithSQPollThreadIdle(10*time.Millisecond))
+	if err != nil {
+		t.Skip(err)
+	}
+	defer iour.Close()
+
+	for i := 0; i < 3; i++ {
+		// the poll thread goes to sleep and must be woken up by the submission
+		time.Sleep(50 * time.Millisecond)
+
+		request, err := iour.SubmitRequest(Nop(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-request.Done():
+		case <-time.After(time.Second):
+			t.Fatal("request is not completed after the poll thread is idle")
+		}
+	}
+}
+
+func benchmarkNop(b *testing.B, opts ...IOURingOption) {
+	iour, err := New(64, opts...)
+	if err != nil {
+		b.Skip(err)
+	}
+	defer iour.Close()
+
+	requests := make([]PrepRequest, 32)
+	for i := range requests {
+		requests[i] = Nop()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set, err := iour.SubmitRequests(requests, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		<-set.Done()
+	}
+}
+
+func BenchmarkNop(b *testing.B) {
+	b.Run("default", func(b *testing.B) { benchmarkNop(b) })
+	b.Run("sqpoll", func(b *testing.B) { benchmarkNop(b, WithSQPoll(), WithSQPollThreadIdle(100*time.Millisecond)) })
+}
+
+func TestSQPollThreadIdleOption(t *testing.T) {
+	for _, idle := range []time.Duration{-time.Millisecond, (math.MaxUint32 + 1) * time.Millisecond, math.MaxInt64} {
+		if _, err := New(1, WithSQPollThreadIdle(idle)); err == nil {
+			t.Fatalf("New with sq poll thread idle %v returns nil error", idle)
+		}
+	}
+
+	iour := &IOURing{params: &iouring_syscall.IOURingParams{}}
+	WithSQPollThreadIdle(1500 * time.Microsecond)(iour)
+	if iour.optionErr != nil || iour.params.SQThreadIdle != 2 {
+		t.Fatalf("sq thread idle is %d, %v, want 2", iour.params.SQThreadIdle, iour.optionErr)
+	}
+}
+
+func TestIOPoll(t *testing.T) {
+	iour, err := New(8, WithIOPoll())
+	if err != nil {
+		t.Skip(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	request, err := iour.SubmitRequest(Pread(int(f.Fd()), make([]byte, 1), 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if err := request.Err(); err != ErrNotDirectIO {
+		t.Fatalf("read of file without O_DIRECT: %v, want %v", err, ErrNotDirectIO)
+	}
+
+	// nop is completed by polling
+	for i := 0; i < 3; i++ {
+		request, err := iour.SubmitRequest(Nop(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-request.Done():
+		case <-time.After(time.Second):
+			t.Fatal("nop is not completed by polling")
+		}
+		if err := request.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCQSizeOption(t *testing.T) {
+	if _, err := New(4, WithCQSize(100)); err == nil {
+		t.Fatal("New with completion queue size 100 returns nil error")
+	}
+	// the submission queue entries are rounded up to 8
+	if _, err := New(5, WithCQSize(4)); err == nil {
+		t.Fatal("New with completion queue smaller than submission queue returns nil error")
+	}
+
+	iour, err := New(4, WithCQSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if entries := iour.params.CQEntries; entries != 64 {
+		t.Fatalf("completion queue entries is %d, want 64", entries)
+	}
+}
+
+func TestSetupFlagsOption(t *testing.T) {
+	invalids := []uint32{
+		iouring_syscall.IORING_SETUP_DEFER_TASKRUN | iouring_syscall.IORING_SETUP_SINGLE_ISSUER,
+		iouring_syscall.IORING_SETUP_SINGLE_ISSUER,
+		iouring_syscall.IORING_SETUP_SQPOLL | iouring_syscall.IORING_SETUP_COOP_TASKRUN,
+		iouring_syscall.IORING_SETUP_TASKRUN_FLAG,
+		iouring_syscall.IORING_SETUP_SQ_AFF,
+	}
+	for _, flags := range invalids {
+		if _, err := New(4, WithSetupFlags(flags)); err == nil {
+			t.Fatalf("New with setup flags %#x returns nil error", flags)
+		}
+	}
+
+	flags := iouring_syscall.IORING_SETUP_COOP_TASKRUN | iouring_syscall.IORING_SETUP_TASKRUN_FLAG
+	iour, err := New(4, WithSetupFlags(flags))
+	if err != nil {
+		t.Skipf("kernel doesn't support IORING_SETUP_COOP_TASKRUN: %v", err)
+	}
+	defer iour.Close()
+
+	if iour.Flags&flags != flags {
+		t.Fatalf("setup flags is %#x, want %#x set", iour.Flags, flags)
+	}
+	if _, err := iour.SubmitRequestSyncWithTimeout(Nop(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSingleIssuer(t *testing.T) {
+	// the requests are submitted by the goroutines of the package
+	// which are not the thread creating the ring
+	params := &iouring_syscall.IOURingParams{Flags: iouring_syscall.IORING_SETUP_SINGLE_ISSUER}
+	if _, err := New(4, WithParams(params)); err == nil {
+		t.Fatal("New with IORING_SETUP_SINGLE_ISSUER returns nil error")
+	}
+}
+
+func TestErrClosed(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := iour.SubmitRequestSyncWithTimeout(Nop(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-iour.Err():
+		t.Fatalf("Err receives %v before Close", err)
+	default:
+	}
+
+	iour.Close()
+	select {
+	case err, ok := <-iour.Err():
+		if ok {
+			t.Fatalf("Err receives %v, want closed channel", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Err is not closed by Close")
+	}
+}
+
+func testSubmitInvalidRequest(t *testing.T, opts ...IOURingOption) {
+	iour, err := New(8, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	// the kernel fails the request with the unknown opcode by EINVAL
+	invalid := func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = errResolver
+		sqe.PrepOperation(0xff, -1, 0, 0, 0)
+	}
+
+	requests, err := iour.SubmitRequests([]PrepRequest{Nop(), invalid, Nop(), Nop()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-requests.Done():
+	case <-time.After(time.Second):
+		t.Fatal("requests after the invalid request are not completed")
+	}
+
+	for i, request := range requests.Requests() {
+		err := request.Err()
+		if i == 1 {
+			if !errors.Is(err, syscall.EINVAL) {
+				t.Fatalf("invalid request error is %v, want %v", err, syscall.EINVAL)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("request %d error is %v", i, err)
+		}
+	}
+}
+
+func TestSubmitInvalidRequest(t *testing.T) {
+	testSubmitInvalidRequest(t)
+}
+
+func TestSubmitAll(t *testing.T) {
+	testSubmitInvalidRequest(t, WithSubmitAll())
+}
+
+func TestAttachWQ(t *testing.T) {
+	primary, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iour, err := New(4, WithAttachWQ(primary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if iour.Flags&iouring_syscall.IORING_SETUP_ATTACH_WQ == 0 {
+		t.Fatal("IORING_SETUP_ATTACH_WQ is not set")
+	}
+	request, err := iour.SubmitRequestSync(Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.Close()
+	if _, err := New(4, WithAttachWQ(primary)); err == nil {
+		t.Fatal("New attached to the closed iouring returns nil error")
+	}
+}
+
+func TestCQOverflowStats(t *testing.T) {
+	iour, err := New(2, WithCQSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	// the run goroutine is blocked by the unread result channel,
+	// so the following completion events overflow the completion queue
+	ch := make(chan Result)
+	if _, err := iour.SubmitRequest(Nop(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := make([]Request, 0, 16)
+	for i := 0; i < 16; i++ {
+		request, err := iour.SubmitRequest(Nop(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, request)
+	}
+	<-ch
+
+	for _, request := range requests {
+		select {
+		case <-request.Done():
+		case <-time.After(time.Second):
+			t.Fatal("overflowed completion event is not flushed")
+		}
+	}
+
+	stats := iour.Stats()
+	if stats.CQOverflows == 0 {
+		t.Fatal("completion queue overflow is not observed")
+	}
+	if stats.CQDropped != 0 {
+		t.Fatalf("%d completion events are dropped", stats.CQDropped)
+	}
+}
+
+// BenchmarkNopRoundTrip measures the latency from the submission to the completion of a request
+func BenchmarkNopRoundTrip(b *testing.B) {
+	iour, err := New(8)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer iour.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		request, err := iour.SubmitRequestSync(Nop())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := request.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNop100k(b *testing.B) {
+	iour, err := New(256)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer iour.Close()
+
+	requests := make([]PrepRequest, 100000)
+	for i := range requests {
+		requests[i] = Nop()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set, err := iour.SubmitRequests(requests, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		<-set.Done()
+	}
+}
+
+// BenchmarkSaturatedSQ submits requests concurrently to a small submission queue,
+// with SQPoll the queue is kept full since the entries are consumed asynchronously
+// by the poller thread. The cpu time spent per request is reported
+func BenchmarkSaturatedSQ(b *testing.B) {
+	b.Run("default", func(b *testing.B) { benchmarkSaturatedSQ(b) })
+	b.Run("sqpoll", func(b *testing.B) { benchmarkSaturatedSQ(b, WithSQPoll(), WithSQPollThreadIdle(100*time.Millisecond)) })
+}
+
+func benchmarkSaturatedSQ(b *testing.B, opts ...IOURingOption) {
+	iour, err := New(2, opts...)
+	if err != nil {
+		b.Skip(err)
+	}
+	defer iour.Close()
+
+	var start, end syscall.Rusage
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &start)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ch := make(chan Result, 1)
+		for pb.Next() {
+			if _, err := iour.SubmitRequest(Nop(), ch); err != nil {
+				b.Error(err)
+				return
+			}
+			<-ch
+		}
+	})
+	b.StopTimer()
+
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &end)
+	cpu := time.Duration(end.Utime.Nano() + end.Stime.Nano() - start.Utime.Nano() - start.Stime.Nano())
+	b.ReportMetric(float64(cpu.Nanoseconds())/float64(b.N), "cpu-ns/op")
+}
+
+func TestExternalEventLoop(t *testing.T) {
+	if _, err := New(8, WithExternalEventLoop(), WithIOPoll()); err == nil {
+		t.Fatal("New with external event loop and IOPOLL returns nil error")
+	}
+
+	iour, err := New(8, WithExternalEventLoop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(epfd)
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, iour.Eventfd(),
+		&syscall.EpollEvent{Fd: int32(iour.Eventfd()), Events: syscall.EPOLLIN},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Result, 1)
+	if _, err := iour.SubmitRequest(Nop(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make([]syscall.EpollEvent, 1)
+	if n, err := syscall.EpollWait(epfd, events, 1000); err != nil || n != 1 {
+		t.Fatalf("wait for the eventfd: %d, %v", n, err)
+	}
+	if n, err := iour.ReapCompletions(); err != nil || n != 1 {
+		t.Fatalf("reap completions: %d, %v, want 1", n, err)
+	}
+	if err := (<-ch).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the probe of the cancel flags doesn't wait for the event loop
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := iour.CancelAll(nil)
+		canceled <- err
+	}()
+	select {
+	case err := <-canceled:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CancelAll waits for the completions reaped by the event loop")
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&iour.cancelFlags) == probing; i++ {
+		if _, err := syscall.EpollWait(epfd, events, 10); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := iour.ReapCompletions(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if flags := atomic.LoadInt32(&iour.cancelFlags); flags != probeSupported && flags != probeUnsupported {
+		t.Fatalf("cancel flags are not probed: %d", flags)
+	}
+
+	// the in-flight requests are reaped by Close
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	request, err := iour.SubmitRequest(Read(int(r.Fd()), make([]byte, 1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if err := request.Err(); err != ErrIOURingClosed {
+		t.Fatalf("in-flight request: %v, want %v", err, ErrIOURingClosed)
+	}
+	if _, err := iour.ReapCompletions(); err != ErrIOURingClosed {
+		t.Fatalf("reap completions after close: %v, want %v", err, ErrIOURingClosed)
+	}
+}
+
+func TestWaitCQEvents(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if err := iour.WaitCQEvents(1, 10*time.Millisecond); err != ErrWaitTimeout {
+		t.Fatalf("wait without requests: %v, want %v", err, ErrWaitTimeout)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- iour.WaitCQEvents(2, time.Second)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := iour.SubmitRequests([]PrepRequest{Nop(), Timeout(20 * time.Millisecond)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		iour.Close()
+	}()
+	if err := iour.WaitCQEvents(1, time.Second); err != ErrIOURingClosed {
+		t.Fatalf("wait on the closed ring: %v, want %v", err, ErrIOURingClosed)
 	}
 }
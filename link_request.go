@@ -13,10 +13,17 @@ import (
 	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
 
+// SubmitLinkRequests submit the requests as a chain with IOSQE_IO_LINK set on every request
+// but the last, the requests are started in order after the previous one is completed.
+// If a request fails, the rest of the chain is completed with ErrRequestCanceled.
+// The chain is submitted at once, so it must fit in the submission queue
 func (iour *IOURing) SubmitLinkRequests(requests []PrepRequest, ch chan<- Result) (RequestSet, error) {
 	return iour.submitLinkRequest(requests, ch, false)
 }
 
+// SubmitHardLinkRequests submit the requests as a chain like SubmitLinkRequests
+// with IOSQE_IO_HARDLINK, the chain is not broken if a request fails,
+// such as a short read or a timeout expiration, the rest of the chain is still started
 func (iour *IOURing) SubmitHardLinkRequests(requests []PrepRequest, ch chan<- Result) (RequestSet, error) {
 	return iour.submitLinkRequest(requests, ch, true)
 }
@@ -38,6 +45,9 @@ func (iour *IOURing) submitLinkRequest(requests []PrepRequest, ch chan<- Result,
 	if iour.IsClosed() {
 		return nil, ErrIOURingClosed
 	}
+	if iour.disabled {
+		return nil, ErrIOURingDisabled
+	}
 
 	var sqeN uint32
 	userDatas := make([]*UserData, 0, len(requests))
@@ -63,26 +73,54 @@ func (iour *IOURing) submitLinkRequest(requests []PrepRequest, ch chan<- Result,
 	// issue: https://github.com/Iceber/iouring-go/issues/8
 	rset := newRequestSet(userDatas)
 
-	iour.userDataLock.Lock()
-	for _, data := range userDatas {
-		iour.userDatas[data.id] = data
+	n, err := iour.submitUserDatas(userDatas)
+	if err != nil {
+		if n == 0 {
+			return nil, err
+		}
+		// the submitted requests of the chain are still notified via channel
+		rset.truncate(n)
+		return rset, err
 	}
-	iour.userDataLock.Unlock()
 
-	if _, err := iour.submit(); err != nil {
-		iour.userDataLock.Lock()
-		for _, data := range userDatas {
-			delete(iour.userDatas, data.id)
+	return rset, nil
+}
+
+// Link link the requests to be executed in order, the rest of the link is canceled
+// if a request fails. The returned requests can be submitted with other requests
+// by SubmitRequests, the link is never split into different submissions
+func Link(requests ...PrepRequest) []PrepRequest {
+	return link(requests, iouring_syscall.IOSQE_FLAGS_IO_LINK)
+}
+
+// HardLink link the requests to be executed in order like Link,
+// but the rest of the link is not canceled if a request fails
+func HardLink(requests ...PrepRequest) []PrepRequest {
+	return link(requests, iouring_syscall.IOSQE_FLAGS_IO_HARDLINK)
+}
+
+func link(requests []PrepRequest, flags uint8) []PrepRequest {
+	linked := make([]PrepRequest, len(requests))
+	for i := range requests {
+		prepReq := requests[i]
+		if i == len(requests)-1 {
+			linked[i] = prepReq
+			break
 		}
-		iour.userDataLock.Unlock()
 
-		return nil, err
+		linked[i] = func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+			prepReq(sqe, userData)
+			sqe.SetFlags(flags)
+		}
 	}
-
-	return rset, nil
+	return linked
 }
 
-func linkTimeout(t time.Duration) PrepRequest {
+// LinkTimeout cancel the previous request in the link if it's not completed before the timeout,
+// it must be placed right after the request in SubmitLinkRequests or SubmitHardLinkRequests,
+// and the canceled request is completed with ErrRequestCanceled.
+// The completion of LinkTimeout itself is not notified via channel
+func LinkTimeout(t time.Duration) PrepRequest {
 	timespec := unix.NsecToTimespec(t.Nanoseconds())
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
@@ -0,0 +1,46 @@
+// +build linux
+
+package iouring
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestLinkAndHardLink(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	buf := make([]byte, 1)
+	requests := append(
+		Link(Read(-1, buf), Nop(), Nop()),
+		HardLink(Read(-1, buf), Nop(), Nop())...,
+	)
+	rset, err := iour.SubmitRequests(requests, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-rset.Done()
+
+	results := rset.Requests()
+	for _, i := range []int{0, 3} {
+		if err := results[i].Err(); err != syscall.EBADF {
+			t.Fatalf("request %d: %v, want %v", i, err, syscall.EBADF)
+		}
+	}
+	// the rest of the link is canceled
+	for _, i := range []int{1, 2} {
+		if err := results[i].Err(); err != ErrRequestCanceled {
+			t.Fatalf("request %d: %v, want %v", i, err, ErrRequestCanceled)
+		}
+	}
+	// the rest of the hard link is executed
+	for _, i := range []int{4, 5} {
+		if err := results[i].Err(); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+}
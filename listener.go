@@ -0,0 +1,182 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+var _ net.Listener = &Listener{}
+
+// Listener is a net.Listener whose accepts are submitted to IOURing,
+// the accepted connections are returned as Conn.
+// It's safe for concurrent use by multiple goroutines.
+//
+// Each Accept submits a single accept request rather than a multishot accept,
+// the connections accepted by a multishot accept while nobody is calling Accept
+// would be queued on the result channel and block the completion of other requests.
+type Listener struct {
+	iour *IOURing
+	fd   int
+	addr net.Addr
+
+	lock   sync.Mutex
+	closed bool
+
+	// accepts are the in-flight accept requests, they are canceled when the listener is closed
+	accepts map[Request]struct{}
+}
+
+// Listen announce on the local network address like net.Listen,
+// the network must be "tcp", "tcp4" or "tcp6"
+func Listen(iour *IOURing, network, address string) (*Listener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &net.OpError{Op: "listen", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	file, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the fd of file is closed with file, so it's duplicated for the listener
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		return nil, os.NewSyscallError("dup", err)
+	}
+	syscall.CloseOnExec(fd)
+
+	listener, err := NewListener(iour, fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return listener, nil
+}
+
+// NewListener return a Listener of the listening socket fd,
+// the fd is owned by Listener and closed by Listener.Close
+func NewListener(iour *IOURing, fd int) (*Listener, error) {
+	accepting, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_ACCEPTCONN)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	if accepting == 0 {
+		return nil, errors.New("socket is not listening")
+	}
+
+	sotype, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockname", err)
+	}
+
+	return &Listener{
+		iour:    iour,
+		fd:      fd,
+		addr:    sockaddrToAddr(sotype, sa),
+		accepts: make(map[Request]struct{}),
+	}, nil
+}
+
+// Fd returns the socket fd of the listener
+func (ln *Listener) Fd() int {
+	return ln.fd
+}
+
+// Accept wait for and return the next connection to the listener
+func (ln *Listener) Accept() (net.Conn, error) {
+	ln.lock.Lock()
+	if ln.closed {
+		ln.lock.Unlock()
+		return nil, ln.opError("accept", ErrConnClosed)
+	}
+
+	request, err := ln.iour.SubmitRequest(Accept4(ln.fd, syscall.SOCK_CLOEXEC), nil)
+	if err != nil {
+		ln.lock.Unlock()
+		return nil, ln.opError("accept", err)
+	}
+	ln.accepts[request] = struct{}{}
+	ln.lock.Unlock()
+
+	<-request.Done()
+
+	ln.lock.Lock()
+	delete(ln.accepts, request)
+	closed := ln.closed
+	ln.lock.Unlock()
+
+	fd, err := request.ReturnFd()
+	if err != nil {
+		if err == ErrRequestCanceled && closed {
+			err = ErrConnClosed
+		}
+		return nil, ln.opError("accept", err)
+	}
+
+	conn, err := NewConn(ln.iour, fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, ln.opError("accept", err)
+	}
+	return conn, nil
+}
+
+// Close cancel the in-flight accepts and close the listener by a close request
+func (ln *Listener) Close() error {
+	ln.lock.Lock()
+	if ln.closed {
+		ln.lock.Unlock()
+		return ln.opError("close", ErrConnClosed)
+	}
+	ln.closed = true
+	requests := make([]Request, 0, len(ln.accepts))
+	for request := range ln.accepts {
+		requests = append(requests, request)
+	}
+	ln.lock.Unlock()
+
+	for _, request := range requests {
+		_, _ = request.Cancel()
+	}
+
+	request, err := ln.iour.SubmitRequestSync(Close(ln.fd))
+	if err == nil {
+		err = request.Err()
+	}
+	if err != nil {
+		return ln.opError("close", err)
+	}
+	return nil
+}
+
+// Addr returns the listener's network address
+func (ln *Listener) Addr() net.Addr {
+	return ln.addr
+}
+
+func (ln *Listener) opError(op string, err error) error {
+	var network string
+	if ln.addr != nil {
+		network = ln.addr.Network()
+	}
+	return &net.OpError{Op: op, Net: network, Addr: ln.addr, Err: err}
+}
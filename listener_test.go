@@ -0,0 +1,98 @@
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, err := Listen(iour, "tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := []byte("hello iouring")
+		if _, err := conn.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != string(msg) {
+			t.Fatalf("echo is %q, want %q", buf, msg)
+		}
+		conn.Close()
+	}
+}
+
+func TestListenerCloseCancelAccept(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, err := Listen(iour, "tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		errCh <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := ln.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrConnClosed) {
+			t.Fatalf("accept error is %v, want %v", err, ErrConnClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("accept is not canceled by Close")
+	}
+
+	if _, err := net.Dial("tcp4", ln.Addr().String()); err == nil {
+		t.Fatal("listener is still accepting after Close")
+	}
+	if err := ln.Close(); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("second Close returns %v, want %v", err, ErrConnClosed)
+	}
+}
@@ -4,6 +4,11 @@
 package iouring
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
 	"time"
 
 	iouring_syscall "github.com/iceber/iouring-go/syscall"
@@ -11,16 +16,30 @@ import (
 
 type IOURingOption func(*IOURing)
 
-// WithSQPoll a kernel thread is created to perform submission queue polling
+// WithSQPoll a kernel thread is created to perform submission queue polling,
+// the submissions don't need io_uring_enter while the thread is awake.
 // In Version 5.10 and later, allow using this as non-root,
-// if the user has the CAP_SYS_NICE capability
+// if the user has the CAP_SYS_NICE capability.
+// Before version 5.11, the files must be registered by RegisterFiles,
+// the requests with unregistered files fail with ErrUnregisteredFile
+// if IORING_FEAT_SQPOLL_NONFIXED is not supported
 func WithSQPoll() IOURingOption {
 	return func(iour *IOURing) {
 		iour.params.Flags |= iouring_syscall.IORING_SETUP_SQPOLL
 	}
 }
 
-// WithSQPollThreadCPU the poll thread will be bound to the cpu set, only meaningful when WithSQPoll option
+// WithIOPoll the io_uring instance is busy-waiting for the completions instead of interrupts,
+// the completions are polled by the run goroutine while there are in-flight requests.
+// Only the reads and writes of the files opened with O_DIRECT on the supported
+// block devices (eg. NVMe) are allowed, the requests of other files are completed with ErrNotDirectIO
+func WithIOPoll() IOURingOption {
+	return func(iour *IOURing) {
+		iour.params.Flags |= iouring_syscall.IORING_SETUP_IOPOLL
+	}
+}
+
+// WithSQPollThreadCPU the poll thread will be bound to the cpu set, requires WithSQPoll option
 func WithSQPollThreadCPU(cpu uint32) IOURingOption {
 	return func(iour *IOURing) {
 		iour.params.Flags |= iouring_syscall.IORING_SETUP_SQ_AFF
@@ -28,9 +47,19 @@ func WithSQPollThreadCPU(cpu uint32) IOURingOption {
 	}
 }
 
+// WithSQPollThreadIdle the poll thread goes to sleep after being idle for idle,
+// only meaningful when WithSQPoll option, the kernel default is one second.
+// The idle time is rounded up to milliseconds, New returns an error
+// if it's negative or doesn't fit in the 32-bit milliseconds of the kernel
 func WithSQPollThreadIdle(idle time.Duration) IOURingOption {
 	return func(iour *IOURing) {
-		iour.params.SQThreadIdle = uint32(idle / time.Millisecond)
+		// checked before rounding up, which overflows for the huge durations
+		if idle < 0 || idle > math.MaxUint32*time.Millisecond {
+			iour.setOptionErr(fmt.Errorf("invalid sq poll thread idle %v", idle))
+			return
+		}
+		ms := (idle + time.Millisecond - 1) / time.Millisecond
+		iour.params.SQThreadIdle = uint32(ms)
 	}
 }
 
@@ -41,24 +70,65 @@ func WithParams(params *iouring_syscall.IOURingParams) IOURingOption {
 	}
 }
 
-// WithCQSize create the completion queue with size entries
-// size must bue greater than entries
+// WithSetupFlags the IORING_SETUP_* flags are added to the setup flags, for the
+// flags that have no option, New returns an error for the invalid combinations.
+// With IORING_SETUP_COOP_TASKRUN, the completions that are posted by task work
+// are delayed until the submitting thread enters the kernel.
+// IORING_SETUP_SINGLE_ISSUER and IORING_SETUP_DEFER_TASKRUN are not supported,
+// the requests are also submitted by the goroutines of the package, such as
+// the cancellations of Close and SubmitRequestWithContext, and the completions
+// are reaped by the run goroutine which is not the submitting thread
+func WithSetupFlags(flags uint32) IOURingOption {
+	return func(iour *IOURing) {
+		iour.params.Flags |= flags
+	}
+}
+
+// WithSubmitAll the kernel continues to submit the following requests of a submission
+// if a request fails to be submitted, instead of stopping at the failed request.
+// Available since 5.18
+func WithSubmitAll() IOURingOption {
+	return func(iour *IOURing) {
+		iour.params.Flags |= iouring_syscall.IORING_SETUP_SUBMIT_ALL
+	}
+}
+
+// WithCQSize create the completion queue with size entries,
+// size must be a power of 2 and not less than the submission queue entries.
+// By default the completion queue is twice the submission queue, SubmitRequests
+// submits more requests than the submission queue entries in chunks, so the
+// in-flight requests may exceed it. The completion events that don't fit in the
+// completion queue are kept in the overflow list of the kernel and flushed by
+// io_uring_enter, which is much slower, a larger completion queue avoids it
 func WithCQSize(size uint32) IOURingOption {
 	return func(iour *IOURing) {
+		if size == 0 || size&(size-1) != 0 {
+			iour.setOptionErr(fmt.Errorf("invalid completion queue size %d, must be a power of 2", size))
+			return
+		}
 		iour.params.Flags |= iouring_syscall.IORING_SETUP_CQSIZE
 		iour.params.CQEntries = size
 	}
 }
 
 // WithAttachWQ new iouring instance being create will share the asynchronous worker thread
-// backend of the specified io_uring ring, rather than create a new separate thread pool
-func WithAttachWQ(iour *IOURing) IOURingOption {
+// backend of the specified io_uring ring, rather than create a new separate thread pool.
+// The attached ring must not be closed
+func WithAttachWQ(other *IOURing) IOURingOption {
 	return func(iour *IOURing) {
+		if other == nil || other.IsClosed() {
+			iour.setOptionErr(errors.New("attached iouring is closed"))
+			return
+		}
 		iour.params.Flags |= iouring_syscall.IORING_SETUP_ATTACH_WQ
-		iour.params.WQFd = uint32(iour.fd)
+		iour.params.WQFd = uint32(other.fd)
 	}
 }
 
+// WithAsync IOSQE_ASYNC is set on every request, the requests are always issued
+// by the asynchronous worker threads instead of being tried inline first.
+// PrepRequest.WithAsync sets it on individual requests, it cannot be unset
+// on a request when this option is used
 func WithAsync() IOURingOption {
 	return func(iour *IOURing) {
 		iour.async = true
@@ -94,3 +164,62 @@ func WithCQE32() IOURingOption {
 		iour.params.Flags |= iouring_syscall.IORING_SETUP_CQE32
 	}
 }
+
+// WithExternalEventLoop the completion events are not reaped by the run goroutine,
+// the eventfd returned by IOURing.Eventfd is polled by the event loop of the caller,
+// and IOURing.ReapCompletions is called when it's readable.
+// The results are sent on the channels by ReapCompletions, so the channels must be buffered
+// or received by other goroutines. The APIs waiting for the completions, such as
+// SubmitRequestSync, SubmitRequestSyncWithTimeout, WaitCQEvents and the methods of Conn,
+// File and Listener, block forever if they are called by the goroutine of the event loop.
+// It cannot be used with WithIOPoll
+func WithExternalEventLoop() IOURingOption {
+	return func(iour *IOURing) {
+		iour.externalLoop = true
+	}
+}
+
+// WithLogger errors of the completion loop will be written to the logger,
+// nothing is logged by default
+func WithLogger(logger *log.Logger) IOURingOption {
+	return func(iour *IOURing) {
+		if logger == nil {
+			logger = log.New(ioutil.Discard, "", 0)
+		}
+		iour.logger = logger
+	}
+}
+
+// WithDebug every completion event will be written to the logger
+func WithDebug() IOURingOption {
+	return func(iour *IOURing) {
+		iour.debug = true
+	}
+}
+
+func validateSetupFlags(flags uint32) error {
+	if flags&iouring_syscall.IORING_SETUP_DEFER_TASKRUN != 0 {
+		return errors.New("IORING_SETUP_DEFER_TASKRUN is not supported")
+	}
+	if flags&iouring_syscall.IORING_SETUP_SINGLE_ISSUER != 0 {
+		return errors.New("IORING_SETUP_SINGLE_ISSUER is not supported")
+	}
+
+	taskrun := iouring_syscall.IORING_SETUP_COOP_TASKRUN | iouring_syscall.IORING_SETUP_TASKRUN_FLAG
+	if flags&iouring_syscall.IORING_SETUP_SQPOLL != 0 && flags&taskrun != 0 {
+		return errors.New("IORING_SETUP_SQPOLL can't be used with IORING_SETUP_COOP_TASKRUN or IORING_SETUP_TASKRUN_FLAG")
+	}
+	if flags&iouring_syscall.IORING_SETUP_TASKRUN_FLAG != 0 && flags&iouring_syscall.IORING_SETUP_COOP_TASKRUN == 0 {
+		return errors.New("IORING_SETUP_TASKRUN_FLAG requires IORING_SETUP_COOP_TASKRUN")
+	}
+	if flags&iouring_syscall.IORING_SETUP_SQ_AFF != 0 && flags&iouring_syscall.IORING_SETUP_SQPOLL == 0 {
+		return errors.New("IORING_SETUP_SQ_AFF requires IORING_SETUP_SQPOLL")
+	}
+	return nil
+}
+
+func (iour *IOURing) setOptionErr(err error) {
+	if iour.optionErr == nil {
+		iour.optionErr = err
+	}
+}
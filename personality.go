@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// RegisterPersonality register the credentials of the calling thread,
+// the returned personality id can be used by PrepRequest.WithPersonality
+// to issue the requests with the registered credentials.
+// IORING_REGISTER_PERSONALITY is available since 5.6
+func (iour *IOURing) RegisterPersonality() (int, error) {
+	return iouring_syscall.IOURingRegisterResult(iour.fd, iouring_syscall.IORING_REGISTER_PERSONALITY, nil, 0)
+}
+
+// UnregisterPersonality unregister the credentials of the personality id
+func (iour *IOURing) UnregisterPersonality(id int) error {
+	return iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_UNREGISTER_PERSONALITY, nil, uint32(id))
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package iouring
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// registerUnprivilegedPersonality register the personality of the nobody user,
+// the credentials are changed on a locked thread which is terminated afterwards
+func registerUnprivilegedPersonality(t *testing.T, iour *IOURing) int {
+	type result struct {
+		id  int
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		// the goroutine exits without unlocking, so the thread is not reused
+		runtime.LockOSThread()
+
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_SETRESUID, ^uintptr(0), 65534, ^uintptr(0)); errno != 0 {
+			ch <- result{err: errno}
+			return
+		}
+		id, err := iour.RegisterPersonality()
+		ch <- result{id, err}
+	}()
+
+	r := <-ch
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return r.id
+}
+
+func TestPersonality(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the credentials requires root")
+	}
+
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	openat := func(prep PrepRequest, err error) error {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := iour.SubmitRequestSync(prep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fd, err := request.ReturnFd(); err == nil {
+			syscall.Close(fd)
+		}
+		return request.Err()
+	}
+
+	id := registerUnprivilegedPersonality(t, iour)
+
+	prep, err := Openat(unix.AT_FDCWD, f.Name(), syscall.O_RDONLY, 0)
+	if err := openat(prep.WithPersonality(id), err); err != syscall.EACCES {
+		t.Fatalf("open with the unprivileged personality: %v, want %v", err, syscall.EACCES)
+	}
+	if err := openat(Openat(unix.AT_FDCWD, f.Name(), syscall.O_RDONLY, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := iour.UnregisterPersonality(id); err != nil {
+		t.Fatal(err)
+	}
+	prep, err = Openat(unix.AT_FDCWD, f.Name(), syscall.O_RDONLY, 0)
+	if err := openat(prep.WithPersonality(id), err); err != syscall.EINVAL {
+		t.Fatalf("open with the unregistered personality: %v, want %v", err, syscall.EINVAL)
+	}
+}
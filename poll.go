@@ -0,0 +1,40 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// PollAdd poll the fd for the events mask, eg. unix.POLLIN, unix.POLLOUT,
+// the result returns the returned events mask by ReturnInt
+func PollAdd(fd int, events uint32) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_POLL_ADD, int32(fd), 0, 0, 0)
+		sqe.SetOpFlags(events)
+	}
+}
+
+// PollMultishot is PollAdd but notify every time the fd becomes ready,
+// until the request is removed by PollRemove or canceled.
+// Available since 5.13
+func PollMultishot(fd int, events uint32) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_POLL_ADD, int32(fd), 0, iouring_syscall.IORING_POLL_ADD_MULTI, 0)
+		sqe.SetOpFlags(events)
+	}
+}
+
+// PollRemove remove the poll request of the request id returned by Request.ID
+func PollRemove(id uint64) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = removePollResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_POLL_REMOVE, -1, id, 0, 0)
+	}
+}
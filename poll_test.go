@@ -0,0 +1,107 @@
+package iouring
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+func TestPollMultishot(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	ch := make(chan Result, 1)
+	poll, err := iour.SubmitRequest(PollMultishot(int(r.Fd()), unix.POLLIN), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1)
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		result := <-ch
+		if result.Flags()&iouring_syscall.IORING_CQE_F_MORE == 0 {
+			t.Fatal("IORING_CQE_F_MORE is not set on the multishot poll result")
+		}
+		events, err := result.ReturnInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if events&unix.POLLIN == 0 {
+			t.Fatalf("poll events is %#x, want POLLIN", events)
+		}
+
+		if _, err := r.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remove, err := iour.SubmitRequest(PollRemove(poll.ID()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-remove.Done()
+	if err := remove.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-ch).Err(); err != ErrRequestCanceled {
+		t.Fatalf("removed poll error is %v, want %v", err, ErrRequestCanceled)
+	}
+}
+
+func TestPollAdd(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	poll, err := iour.SubmitRequest(PollAdd(int(r.Fd()), unix.POLLIN), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("iouring")); err != nil {
+		t.Fatal(err)
+	}
+
+	<-poll.Done()
+	events, err := poll.ReturnInt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events&unix.POLLIN == 0 {
+		t.Fatalf("poll events is %#x, want POLLIN", events)
+	}
+
+	remove, err := iour.SubmitRequest(PollRemove(poll.ID()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-remove.Done()
+	if err := remove.Err(); err != ErrRequestNotFound {
+		t.Fatalf("remove completed poll error is %v, want %v", err, ErrRequestNotFound)
+	}
+}
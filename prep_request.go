@@ -5,6 +5,7 @@ package iouring
 
 import (
 	"errors"
+	"net"
 	"os"
 	"syscall"
 	"unsafe"
@@ -16,7 +17,7 @@ import (
 
 type PrepRequest func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData)
 
-// WithInfo request with extra info
+// WithInfo attach info to the request, it can be got by Result.GetRequestInfo
 func (prepReq PrepRequest) WithInfo(info interface{}) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		prepReq(sqe, userData)
@@ -24,6 +25,10 @@ func (prepReq PrepRequest) WithInfo(info interface{}) PrepRequest {
 	}
 }
 
+// WithDrain set IOSQE_IO_DRAIN on the request, the request is not started
+// until all previously submitted requests are completed, and later requests
+// are not started until it's completed. It's combined with the flags set by
+// the IOURing options, such as WithAsync
 func (prepReq PrepRequest) WithDrain() PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		prepReq(sqe, userData)
@@ -31,6 +36,26 @@ func (prepReq PrepRequest) WithDrain() PrepRequest {
 	}
 }
 
+// WithAsync set IOSQE_ASYNC on the request, the request is issued by the asynchronous
+// worker threads instead of being tried inline first, such as a request known to block.
+// It's set on every request by the WithAsync option of IOURing
+func (prepReq PrepRequest) WithAsync() PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		prepReq(sqe, userData)
+		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_ASYNC)
+	}
+}
+
+// WithPersonality issue the request with the credentials of the personality id
+// registered by IOURing.RegisterPersonality instead of the credentials of the IOURing
+func (prepReq PrepRequest) WithPersonality(id int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		prepReq(sqe, userData)
+		sqe.SetPersonality(uint16(id))
+	}
+}
+
+// WithCallback attach callback to the request, it's called by Result.Callback
 func (prepReq PrepRequest) WithCallback(callback RequestCallback) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		prepReq(sqe, userData)
@@ -76,10 +101,14 @@ func (iour *IOURing) Pwrite(file *os.File, b []byte, offset uint64, ch chan<- Re
 
 func Nop() PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = errResolver
 		sqe.PrepOperation(iouring_syscall.IORING_OP_NOP, -1, 0, 0, 0)
 	}
 }
 
+// Read read from fd into b, the result returns the number of bytes read by ReturnInt.
+// For seekable files, Read always starts at offset 0, use Pread with offset ^uint64(0)
+// to read from the current file offset
 func Read(fd int, b []byte) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -102,6 +131,7 @@ func Read(fd int, b []byte) PrepRequest {
 	}
 }
 
+// Pread read from fd at offset into b, offset ^uint64(0) means the current file offset
 func Pread(fd int, b []byte, offset uint64) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -124,6 +154,33 @@ func Pread(fd int, b []byte, offset uint64) PrepRequest {
 	}
 }
 
+// ReadFixed read up to len(b) bytes from fd at offset into b, which must be within
+// the buffer registered at bufIndex by IOURing.RegisterBuffers.
+// The result returns the number of bytes read by ReturnInt
+func ReadFixed(fd int, b []byte, bufIndex int, offset uint64) PrepRequest {
+	var bp unsafe.Pointer
+	if len(b) > 0 {
+		bp = unsafe.Pointer(&b[0])
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		userData.SetRequestBuffer(b, nil)
+
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_READ_FIXED,
+			int32(fd),
+			uint64(uintptr(bp)),
+			uint32(len(b)),
+			offset,
+		)
+		sqe.SetBufIndex(uint16(bufIndex))
+	}
+}
+
+// Write write b to fd, the result returns the number of bytes written by ReturnInt.
+// For seekable files, Write always starts at offset 0, use Pwrite with offset ^uint64(0)
+// to write at the current file offset
 func Write(fd int, b []byte) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -146,6 +203,7 @@ func Write(fd int, b []byte) PrepRequest {
 	}
 }
 
+// Pwrite write b to fd at offset, offset ^uint64(0) means the current file offset
 func Pwrite(fd int, b []byte, offset uint64) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -168,6 +226,30 @@ func Pwrite(fd int, b []byte, offset uint64) PrepRequest {
 	}
 }
 
+// WriteFixed write b to fd at offset, b must be within the buffer registered
+// at bufIndex by IOURing.RegisterBuffers.
+// The result returns the number of bytes written by ReturnInt
+func WriteFixed(fd int, b []byte, bufIndex int, offset uint64) PrepRequest {
+	var bp unsafe.Pointer
+	if len(b) > 0 {
+		bp = unsafe.Pointer(&b[0])
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		userData.SetRequestBuffer(b, nil)
+
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_WRITE_FIXED,
+			int32(fd),
+			uint64(uintptr(bp)),
+			uint32(len(b)),
+			offset,
+		)
+		sqe.SetBufIndex(uint16(bufIndex))
+	}
+}
+
 func Readv(fd int, bs [][]byte) PrepRequest {
 	iovecs := bytes2iovec(bs)
 
@@ -179,6 +261,7 @@ func Readv(fd int, bs [][]byte) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&iovecs)
 		userData.request.resolver = fdResolver
 		userData.SetRequestBuffers(bs)
 
@@ -203,6 +286,7 @@ func Preadv(fd int, bs [][]byte, offset uint64) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&iovecs)
 		userData.request.resolver = fdResolver
 		userData.SetRequestBuffers(bs)
 
@@ -226,6 +310,7 @@ func Writev(fd int, bs [][]byte) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&iovecs)
 		userData.request.resolver = fdResolver
 		userData.SetRequestBuffers(bs)
 
@@ -250,6 +335,7 @@ func Pwritev(fd int, bs [][]byte, offset int64) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&iovecs)
 		userData.request.resolver = fdResolver
 		userData.SetRequestBuffers(bs)
 
@@ -263,6 +349,7 @@ func Pwritev(fd int, bs [][]byte, offset int64) PrepRequest {
 	}
 }
 
+// Send send b to the socket with MSG_* flags, the result returns the number of bytes sent by ReturnInt
 func Send(sockfd int, b []byte, flags int) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -272,6 +359,7 @@ func Send(sockfd int, b []byte, flags int) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
 		userData.SetRequestBuffer(b, nil)
 
 		sqe.PrepOperation(
@@ -285,6 +373,7 @@ func Send(sockfd int, b []byte, flags int) PrepRequest {
 	}
 }
 
+// Recv receive from the socket into b with MSG_* flags, the result returns the number of bytes received by ReturnInt
 func Recv(sockfd int, b []byte, flags int) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -294,6 +383,7 @@ func Recv(sockfd int, b []byte, flags int) PrepRequest {
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
 		userData.SetRequestBuffer(b, nil)
 
 		sqe.PrepOperation(
@@ -307,7 +397,51 @@ func Recv(sockfd int, b []byte, flags int) PrepRequest {
 	}
 }
 
+// SendZC send b to the socket with MSG_* flags without copying b,
+// two results are notified via channel: the first one with IORING_CQE_F_MORE returns
+// the number of bytes sent by ReturnInt, and the request is completed with
+// the notification with IORING_CQE_F_NOTIF when b can be reused.
+// The completed request still returns the number of bytes sent.
+// IORING_OP_SEND_ZC is available since 6.0
+func SendZC(sockfd int, b []byte, flags int) PrepRequest {
+	var bp unsafe.Pointer
+	if len(b) > 0 {
+		bp = unsafe.Pointer(&b[0])
+	} else {
+		bp = unsafe.Pointer(&_zero)
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		userData.SetRequestBuffer(b, nil)
+
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_SEND_ZC,
+			int32(sockfd),
+			uint64(uintptr(bp)),
+			uint32(len(b)),
+			0,
+		)
+		sqe.SetOpFlags(uint32(flags))
+	}
+}
+
+// Sendmsg send a message to the socket, the result returns the number of bytes sent by ReturnInt
 func Sendmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepRequest, error) {
+	prepReq, err := SendmsgBuffers(sockfd, [][]byte{p}, oob, to, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		prepReq(sqe, userData)
+		userData.SetRequestBuffer(p, oob)
+		userData.SetRequestBuffers(nil)
+	}, nil
+}
+
+// SendmsgBuffers is Sendmsg with multiple buffers
+func SendmsgBuffers(sockfd int, bs [][]byte, oob []byte, to syscall.Sockaddr, flags int) (PrepRequest, error) {
 	var ptr unsafe.Pointer
 	var salen uint32
 	if to != nil {
@@ -318,33 +452,22 @@ func Sendmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepReq
 		}
 	}
 
-	msg := &syscall.Msghdr{}
+	msg := &unix.Msghdr{}
 	msg.Name = (*byte)(ptr)
 	msg.Namelen = uint32(salen)
-	var iov syscall.Iovec
-	if len(p) > 0 {
-		iov.Base = &p[0]
-		iov.SetLen(len(p))
+
+	iovecs, empty, err := msgIovecs(sockfd, bs, oob)
+	if err != nil {
+		return nil, err
+	}
+	if len(iovecs) > 0 {
+		msg.Iov = (*unix.Iovec)(unsafe.Pointer(&iovecs[0]))
+		msg.SetIovlen(len(iovecs))
 	}
-	var dummy byte
 	if len(oob) > 0 {
-		if len(p) == 0 {
-			var sockType int
-			sockType, err := syscall.GetsockoptInt(sockfd, syscall.SOL_SOCKET, syscall.SO_TYPE)
-			if err != nil {
-				return nil, err
-			}
-			// send at least one normal byte
-			if sockType != syscall.SOCK_DGRAM {
-				iov.Base = &dummy
-				iov.SetLen(1)
-			}
-		}
 		msg.Control = &oob[0]
 		msg.SetControllen(len(oob))
 	}
-	msg.Iov = &iov
-	msg.Iovlen = 1
 
 	resolver := func(req Request) {
 		result := req.(*request)
@@ -354,51 +477,64 @@ func Sendmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepReq
 			return
 		}
 
-		if len(oob) > 0 && len(p) == 0 {
+		if empty {
 			result.r0 = 0
 		}
 	}
 
 	msgptr := unsafe.Pointer(msg)
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.hold(msg, to)
+		userData.hold(msg, &iovecs, to)
 		userData.request.resolver = resolver
-		userData.SetRequestBuffer(p, oob)
+		userData.SetRequestBuffer(nil, oob)
+		userData.SetRequestBuffers(bs)
 
 		sqe.PrepOperation(iouring_syscall.IORING_OP_SENDMSG, int32(sockfd), uint64(uintptr(msgptr)), 1, 0)
 		sqe.SetOpFlags(uint32(flags))
 	}, nil
 }
 
+// RecvmsgResult is returned by ReturnValue1 of the Recvmsg request
+type RecvmsgResult struct {
+	Oobn      int              // the length of the received control messages in oob
+	Recvflags int              // flags on the received message, eg. syscall.MSG_TRUNC, syscall.MSG_CTRUNC
+	From      syscall.Sockaddr // the address of the sender, nil if its address family is not supported
+}
+
+// Recvmsg receive a message from the socket, the result returns the number of bytes
+// received into p by ReturnValue0 and a *RecvmsgResult by ReturnValue1
 func Recvmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepRequest, error) {
-	var msg syscall.Msghdr
+	prepReq, err := RecvmsgBuffers(sockfd, [][]byte{p}, oob, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		prepReq(sqe, userData)
+		userData.SetRequestBuffer(p, oob)
+		userData.SetRequestBuffers(nil)
+	}, nil
+}
+
+// RecvmsgBuffers is Recvmsg with multiple buffers
+func RecvmsgBuffers(sockfd int, bs [][]byte, oob []byte, flags int) (PrepRequest, error) {
+	var msg unix.Msghdr
 	var rsa syscall.RawSockaddrAny
 	msg.Name = (*byte)(unsafe.Pointer(&rsa))
 	msg.Namelen = uint32(syscall.SizeofSockaddrAny)
-	var iov syscall.Iovec
-	if len(p) > 0 {
-		iov.Base = &p[0]
-		iov.SetLen(len(p))
+
+	iovecs, empty, err := msgIovecs(sockfd, bs, oob)
+	if err != nil {
+		return nil, err
+	}
+	if len(iovecs) > 0 {
+		msg.Iov = (*unix.Iovec)(unsafe.Pointer(&iovecs[0]))
+		msg.SetIovlen(len(iovecs))
 	}
-	var dummy byte
 	if len(oob) > 0 {
-		if len(p) == 0 {
-			var sockType int
-			sockType, err := syscall.GetsockoptInt(sockfd, syscall.SOL_SOCKET, syscall.SO_TYPE)
-			if err != nil {
-				return nil, err
-			}
-			// receive at least one normal byte
-			if sockType != syscall.SOCK_DGRAM {
-				iov.Base = &dummy
-				iov.SetLen(1)
-			}
-		}
 		msg.Control = &oob[0]
 		msg.SetControllen(len(oob))
 	}
-	msg.Iov = &iov
-	msg.Iovlen = 1
 
 	resolver := func(req Request) {
 		result := req.(*request)
@@ -408,15 +544,23 @@ func Recvmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepReq
 			return
 		}
 
-		if len(oob) > 0 && len(p) == 0 {
+		if empty {
 			result.r0 = 0
 		}
+
+		info := &RecvmsgResult{Oobn: int(msg.Controllen), Recvflags: int(msg.Flags)}
+		if rsa.Addr.Family != syscall.AF_UNSPEC {
+			// the message is received even if the address family is not supported
+			info.From, _ = anyToSockaddr(&rsa)
+		}
+		result.r1 = info
 	}
 
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.hold(&msg, &rsa)
+		userData.hold(&msg, &rsa, &iovecs)
 		userData.request.resolver = resolver
-		userData.SetRequestBuffer(p, oob)
+		userData.SetRequestBuffer(nil, oob)
+		userData.SetRequestBuffers(bs)
 
 		sqe.PrepOperation(
 			iouring_syscall.IORING_OP_RECVMSG,
@@ -429,33 +573,36 @@ func Recvmsg(sockfd int, p, oob []byte, to syscall.Sockaddr, flags int) (PrepReq
 	}, nil
 }
 
-func Accept(sockfd int) PrepRequest {
-	var rsa syscall.RawSockaddrAny
-	var len uint32 = syscall.SizeofSockaddrAny
+// msgIovecs return the iovecs of the message buffers,
+// empty reports whether a dummy byte is used because of the empty buffers with oob
+func msgIovecs(sockfd int, bs [][]byte, oob []byte) (iovecs []syscall.Iovec, empty bool, err error) {
+	var length int
+	for _, b := range bs {
+		length += len(b)
+	}
 
-	resolver := func(req Request) {
-		result := req.(*request)
-		fd := int(result.res)
-		errResolver(result)
-		if result.err != nil {
-			return
+	if len(oob) > 0 && length == 0 {
+		var sockType int
+		sockType, err = syscall.GetsockoptInt(sockfd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+		if err != nil {
+			return nil, false, err
 		}
-
-		result.r0 = fd
-		result.r1, result.err = anyToSockaddr(&rsa)
-		if result.err != nil {
-			syscall.Close(fd)
-			result.r0 = 0
+		// send or receive at least one normal byte
+		if sockType != syscall.SOCK_DGRAM {
+			return bytes2iovec([][]byte{make([]byte, 1)}), true, nil
 		}
 	}
+	return bytes2iovec(bs), false, nil
+}
 
-	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.hold(&len)
-		userData.request.resolver = resolver
-		sqe.PrepOperation(iouring_syscall.IORING_OP_ACCEPT, int32(sockfd), uint64(uintptr(unsafe.Pointer(&rsa))), 0, uint64(uintptr(unsafe.Pointer(&len))))
-	}
+// Accept accept a connection on the socket,
+// the result returns the connection fd by ReturnValue0 and the peer syscall.Sockaddr by ReturnValue1,
+// the peer net.Addr is returned by AcceptedAddr
+func Accept(sockfd int) PrepRequest {
+	return Accept4(sockfd, 0)
 }
 
+// Accept4 is Accept with flags, eg. syscall.SOCK_NONBLOCK, syscall.SOCK_CLOEXEC
 func Accept4(sockfd int, flags int) PrepRequest {
 	var rsa syscall.RawSockaddrAny
 	var len uint32 = syscall.SizeofSockaddrAny
@@ -494,6 +641,37 @@ func Accept4(sockfd int, flags int) PrepRequest {
 	}
 }
 
+// AcceptedAddr returns the peer net.Addr of the completed Accept request,
+// nil if the request failed or the address family is not supported
+func AcceptedAddr(result Result) net.Addr {
+	sa, ok := result.ReturnValue1().(syscall.Sockaddr)
+	if !ok {
+		return nil
+	}
+
+	// the unix address depends on the socket type of the connection
+	sotype := syscall.SOCK_STREAM
+	if fd, err := result.ReturnFd(); err == nil {
+		if typ, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE); err == nil {
+			sotype = typ
+		}
+	}
+	return sockaddrToAddr(sotype, sa)
+}
+
+// MultishotAccept accept connections on the socket continuously by one request,
+// every accepted connection is notified via channel and its fd is returned by ReturnFd.
+// Available since 5.19
+func MultishotAccept(sockfd int, flags int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_ACCEPT, int32(sockfd), 0, 0, 0)
+		sqe.SetOpFlags(uint32(flags))
+		sqe.SetIoprio(iouring_syscall.IORING_ACCEPT_MULTISHOT)
+	}
+}
+
 func Connect(sockfd int, sa syscall.Sockaddr) (PrepRequest, error) {
 	ptr, n, err := sockaddr(sa)
 	if err != nil {
@@ -514,6 +692,17 @@ func Connect(sockfd int, sa syscall.Sockaddr) (PrepRequest, error) {
 	}, nil
 }
 
+// ConnectAddr is Connect with *net.TCPAddr, *net.UDPAddr or *net.UnixAddr
+func ConnectAddr(sockfd int, addr net.Addr) (PrepRequest, error) {
+	sa, err := addrToSockaddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return Connect(sockfd, sa)
+}
+
+// Openat open the path relative to dirfd, or the current working directory if dirfd is unix.AT_FDCWD,
+// the result returns the opened fd by ReturnFd
 func Openat(dirfd int, path string, flags uint32, mode uint32) (PrepRequest, error) {
 	flags |= syscall.O_LARGEFILE
 	b, err := syscall.ByteSliceFromString(path)
@@ -537,6 +726,8 @@ func Openat(dirfd int, path string, flags uint32, mode uint32) (PrepRequest, err
 	}, nil
 }
 
+// Openat2 is Openat with unix.OpenHow, whose Resolve field can restrict the path resolution,
+// eg. unix.RESOLVE_BENEATH, unix.RESOLVE_NO_SYMLINKS
 func Openat2(dirfd int, path string, how *unix.OpenHow) (PrepRequest, error) {
 	b, err := syscall.ByteSliceFromString(path)
 	if err != nil {
@@ -545,7 +736,7 @@ func Openat2(dirfd int, path string, how *unix.OpenHow) (PrepRequest, error) {
 
 	bp := unsafe.Pointer(&b[0])
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.hold(&b)
+		userData.hold(&b, how)
 		userData.request.resolver = fdResolver
 
 		sqe.PrepOperation(
@@ -558,15 +749,25 @@ func Openat2(dirfd int, path string, how *unix.OpenHow) (PrepRequest, error) {
 	}, nil
 }
 
+// Statx get the file status into stat, the result returns stat by ReturnValue0.
+// With the flag unix.AT_EMPTY_PATH and the empty path, the status of dirfd is returned
 func Statx(dirfd int, path string, flags uint32, mask int, stat *unix.Statx_t) (PrepRequest, error) {
 	b, err := syscall.ByteSliceFromString(path)
 	if err != nil {
 		return nil, err
 	}
 
+	resolver := func(req Request) {
+		result := req.(*request)
+		if errResolver(result); result.err != nil {
+			return
+		}
+		result.r0 = stat
+	}
+
 	bp := unsafe.Pointer(&b[0])
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.request.resolver = errResolver
+		userData.request.resolver = resolver
 		userData.hold(&b, stat)
 
 		sqe.PrepOperation(
@@ -580,6 +781,8 @@ func Statx(dirfd int, path string, flags uint32, mask int, stat *unix.Statx_t) (
 	}, nil
 }
 
+// Fsync synchronize the file's in-core state with storage device,
+// link it after write requests to make sure it is started after the writes are completed
 func Fsync(fd int) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = errResolver
@@ -587,6 +790,8 @@ func Fsync(fd int) PrepRequest {
 	}
 }
 
+// Fdatasync is similar to Fsync, but does not flush modified metadata unless
+// that metadata is needed for a subsequent data retrieval to be correctly handled
 func Fdatasync(fd int) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = errResolver
@@ -595,6 +800,18 @@ func Fdatasync(fd int) PrepRequest {
 	}
 }
 
+// FsyncRange is Fsync only for the range of the file starting at offset with length bytes,
+// offset 0 with length 0 means the whole file
+func FsyncRange(fd int, offset uint64, length uint32) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = errResolver
+		sqe.PrepOperation(iouring_syscall.IORING_OP_FSYNC, int32(fd), 0, length, offset)
+	}
+}
+
+// Fallocate manipulate the allocated disk space of fd in the range [off, off+length),
+// mode is such as unix.FALLOC_FL_KEEP_SIZE and unix.FALLOC_FL_PUNCH_HOLE.
+// The kernel takes the length from the addr field and the mode from the len field of the sqe
 func Fallocate(fd int, mode uint32, off int64, length int64) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = errResolver
@@ -609,6 +826,7 @@ func Fallocate(fd int, mode uint32, off int64, length int64) PrepRequest {
 	}
 }
 
+// Close close the fd, the fd is also unregistered from the FileRegister if it's registered
 func Close(fd int) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = errResolver
@@ -616,6 +834,67 @@ func Close(fd int) PrepRequest {
 	}
 }
 
+// Shutdown shut down part of a full-duplex connection of the socket,
+// how is one of syscall.SHUT_RD, syscall.SHUT_WR and syscall.SHUT_RDWR.
+// The kernel takes how from the len field of the sqe
+func Shutdown(sockfd int, how int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = errResolver
+		sqe.PrepOperation(iouring_syscall.IORING_OP_SHUTDOWN, int32(sockfd), 0, uint32(how), 0)
+	}
+}
+
+// Splice move n bytes from fdIn at offIn to fdOut at offOut, one of the fds must be a pipe,
+// the offset of the pipe must be -1, and -1 for other fds means the current file offset.
+// fdIn and fdOut are used as fixed files if they are registered.
+// The result returns the number of bytes moved by ReturnInt
+func Splice(fdIn int, offIn int64, fdOut int, offOut int64, n uint32, flags uint32) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_SPLICE,
+			int32(fdOut),
+			uint64(offIn),
+			n,
+			uint64(offOut),
+		)
+		sqe.SetSpliceFdIn(int32(fdIn))
+		sqe.SetOpFlags(flags)
+	}
+}
+
+// Tee duplicate n bytes from the pipe fdIn to the pipe fdOut without consuming them,
+// the result returns the number of bytes duplicated by ReturnInt
+func Tee(fdIn int, fdOut int, n uint32, flags uint32) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_TEE, int32(fdOut), 0, n, 0)
+		sqe.SetSpliceFdIn(int32(fdIn))
+		sqe.SetOpFlags(flags)
+	}
+}
+
+// Fadvise announce an intention to access the data of fd in the range [offset, offset+length)
+// in a specific pattern, advice is such as unix.FADV_DONTNEED, the length 0 means until the end of the file
+func Fadvise(fd int, offset uint64, length uint32, advice int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = errResolver
+
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_FADVISE,
+			int32(fd),
+			0,
+			length,
+			offset,
+		)
+		sqe.SetOpFlags(uint32(advice))
+	}
+}
+
+// Madvise give advice about the use of the memory b, advice is such as unix.MADV_WILLNEED,
+// b is held until the request is completed
 func Madvise(b []byte, advice int) PrepRequest {
 	var bp unsafe.Pointer
 	if len(b) > 0 {
@@ -652,6 +931,8 @@ func EpollCtl(epfd int, op int, fd int, event *syscall.EpollEvent) PrepRequest {
 	}
 }
 
+// Mkdirat create the directory path relative to dirFd with mode,
+// the path is held until the request is completed
 func Mkdirat(dirFd int, path string, mode uint32) (PrepRequest, error) {
 	b, err := syscall.ByteSliceFromString(path)
 	if err != nil {
@@ -673,6 +954,8 @@ func Mkdirat(dirFd int, path string, mode uint32) (PrepRequest, error) {
 	}, nil
 }
 
+// Unlinkat remove the file or the directory with flag unix.AT_REMOVEDIR of path relative to fd,
+// the path is held until the request is completed
 func Unlinkat(fd int, path string, flags int32) (PrepRequest, error) {
 	b, err := syscall.ByteSliceFromString(path)
 	if err != nil {
@@ -695,6 +978,8 @@ func Unlinkat(fd int, path string, flags int32) (PrepRequest, error) {
 	}, nil
 }
 
+// Symlinkat create the symbolic link linkPath relative to newDirFd which contains target,
+// the paths are held until the request is completed
 func Symlinkat(target string, newDirFd int, linkPath string) (PrepRequest, error) {
 	bTarget, err := syscall.ByteSliceFromString(target)
 	if err != nil {
@@ -721,6 +1006,9 @@ func Symlinkat(target string, newDirFd int, linkPath string) (PrepRequest, error
 	}, nil
 }
 
+// Renameat2 rename oldPath relative to oldDirFd to newPath relative to newDirFd,
+// flags is such as unix.RENAME_NOREPLACE, the paths are held until the request is completed.
+// The kernel takes newDirFd from the len field and newPath from the addr2 field of the sqe
 func Renameat2(oldDirFd int, oldPath string, newDirFd int, newPath string, flags int) (PrepRequest, error) {
 	bOldPath, err := syscall.ByteSliceFromString(oldPath)
 	if err != nil {
@@ -739,17 +1027,23 @@ func Renameat2(oldDirFd int, oldPath string, newDirFd int, newPath string, flags
 
 		sqe.PrepOperation(
 			iouring_syscall.IORING_OP_RENAMEAT,
-			int32(oldDirFd), uint64(uintptr(bpOldPath)),
-			uint32(newDirFd), uint64(uintptr(bpNewPath)),
+			int32(oldDirFd),
+			uint64(uintptr(bpOldPath)),
+			uint32(newDirFd),
+			uint64(uintptr(bpNewPath)),
 		)
 		sqe.SetOpFlags(uint32(flags))
 	}, nil
 }
 
+// Renameat rename oldPath relative to oldDirFd to newPath relative to newDirFd
 func Renameat(oldDirFd int, oldPath string, newDirFd int, newPath string) (PrepRequest, error) {
 	return Renameat2(oldDirFd, oldPath, newDirFd, newPath, 0)
 }
 
+// Linkat create the hard link linkPath relative to linkDirFd to targetPath relative to targetDirFd,
+// flags is such as unix.AT_SYMLINK_FOLLOW, the paths are held until the request is completed.
+// The kernel takes linkDirFd from the len field and linkPath from the addr2 field of the sqe
 func Linkat(targetDirFd int, targetPath string, linkDirFd int, linkPath string, flags int) (PrepRequest, error) {
 	bTargetPath, err := syscall.ByteSliceFromString(targetPath)
 	if err != nil {
@@ -763,7 +1057,7 @@ func Linkat(targetDirFd int, targetPath string, linkDirFd int, linkPath string,
 	bpTargetPath := unsafe.Pointer(&bTargetPath[0])
 	bpLinkPath := unsafe.Pointer(&bLinkPath[0])
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
-		userData.hold(&bpTargetPath, &bpLinkPath)
+		userData.hold(&bTargetPath, &bLinkPath)
 		userData.request.resolver = fdResolver
 
 		sqe.PrepOperation(
@@ -776,3 +1070,73 @@ func Linkat(targetDirFd int, targetPath string, linkDirFd int, linkPath string,
 		sqe.SetOpFlags(uint32(flags))
 	}, nil
 }
+
+// Fgetxattr get the extended attribute name of fd into value,
+// the number of bytes of the value is returned by Result.ReturnInt.
+// The name and value are held until the request is completed
+func Fgetxattr(fd int, name string, value []byte) (PrepRequest, error) {
+	return xattr(iouring_syscall.IORING_OP_FGETXATTR, fd, "", name, value, 0)
+}
+
+// Fsetxattr set the extended attribute name of fd to value,
+// flags is such as unix.XATTR_CREATE and unix.XATTR_REPLACE.
+// The name and value are held until the request is completed
+func Fsetxattr(fd int, name string, value []byte, flags int) (PrepRequest, error) {
+	return xattr(iouring_syscall.IORING_OP_FSETXATTR, fd, "", name, value, flags)
+}
+
+// Getxattr get the extended attribute name of path into value,
+// the number of bytes of the value is returned by Result.ReturnInt.
+// The path, name and value are held until the request is completed
+func Getxattr(path string, name string, value []byte) (PrepRequest, error) {
+	return xattr(iouring_syscall.IORING_OP_GETXATTR, -1, path, name, value, 0)
+}
+
+// Setxattr set the extended attribute name of path to value,
+// flags is such as unix.XATTR_CREATE and unix.XATTR_REPLACE.
+// The path, name and value are held until the request is completed
+func Setxattr(path string, name string, value []byte, flags int) (PrepRequest, error) {
+	return xattr(iouring_syscall.IORING_OP_SETXATTR, -1, path, name, value, flags)
+}
+
+// xattr prepare the xattr operations, the kernel takes the name from the addr field,
+// the value from the addr2 field and the path of the path-based operations from the addr3 field
+func xattr(op uint8, fd int, path string, name string, value []byte, flags int) (PrepRequest, error) {
+	bName, err := syscall.ByteSliceFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var bPath []byte
+	var bpPath unsafe.Pointer
+	if op == iouring_syscall.IORING_OP_GETXATTR || op == iouring_syscall.IORING_OP_SETXATTR {
+		if bPath, err = syscall.ByteSliceFromString(path); err != nil {
+			return nil, err
+		}
+		bpPath = unsafe.Pointer(&bPath[0])
+	}
+
+	var bpValue unsafe.Pointer
+	if len(value) > 0 {
+		bpValue = unsafe.Pointer(&value[0])
+	} else {
+		bpValue = unsafe.Pointer(&_zero)
+	}
+
+	bpName := unsafe.Pointer(&bName[0])
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&bName, &bPath)
+		userData.SetRequestBuffer(value, nil)
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(
+			op,
+			int32(fd),
+			uint64(uintptr(bpName)),
+			uint32(len(value)),
+			uint64(uintptr(bpValue)),
+		)
+		sqe.SetOpFlags(uint32(flags))
+		sqe.SetAddr3(uint64(uintptr(bpPath)))
+	}, nil
+}
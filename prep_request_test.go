@@ -0,0 +1,1122 @@
+package iouring
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+func listenTCP(t *testing.T) (*net.TCPListener, int) {
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawConn, err := ln.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fd int
+	if err := rawConn.Control(func(sysfd uintptr) { fd = int(sysfd) }); err != nil {
+		t.Fatal(err)
+	}
+	return ln, fd
+}
+
+func TestMultishotAccept(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, fd := listenTCP(t)
+	defer ln.Close()
+
+	ch := make(chan Result, 1)
+	request, err := iour.SubmitRequest(MultishotAccept(fd, syscall.SOCK_CLOEXEC), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		connFd, err := (<-ch).ReturnFd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		syscall.Close(connFd)
+	}
+
+	if _, err := request.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-ch).Err(); err != ErrRequestCanceled {
+		t.Fatalf("canceled multishot accept error is %v, want %v", err, ErrRequestCanceled)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, _ := listenTCP(t)
+	defer ln.Close()
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+
+	prep, err := ConnectAddr(fd, ln.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequest(prep, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := request.GetRes(); res != 0 {
+		t.Fatalf("connect res is %d, want 0", res)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestPreadv(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.WriteString("io with iouring"); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := [][]byte{make([]byte, 3), make([]byte, 5), make([]byte, 7)}
+	request, err := iour.SubmitRequest(Preadv(int(f.Fd()), bs, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+
+	n, err := request.ReturnInt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 15 {
+		t.Fatalf("read %d bytes, want 15", n)
+	}
+	if got := string(bytes.Join(bs, nil)); got != "io with iouring" {
+		t.Fatalf("read %q, want %q", got, "io with iouring")
+	}
+}
+
+func TestLinkWriteFsync(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	fd := int(f.Fd())
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		Write(fd, []byte("io with iouring")),
+		Fdatasync(fd),
+		FsyncRange(fd, 0, 15),
+		Fsync(fd),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+}
+
+func TestSendRecv(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	buf := make([]byte, 15)
+	requests, err := iour.SubmitRequests([]PrepRequest{
+		Send(fds[0], []byte("io with iouring"), 0),
+		Recv(fds[1], buf, syscall.MSG_WAITALL),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+
+	for _, request := range requests.Requests() {
+		n, err := request.ReturnInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(buf) {
+			t.Fatalf("opcode %d transferred %d bytes, want %d", request.Opcode(), n, len(buf))
+		}
+	}
+	if string(buf) != "io with iouring" {
+		t.Fatalf("received %q, want %q", buf, "io with iouring")
+	}
+}
+
+func TestSendmsgRecvmsgUnixRights(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	f, err := os.Open("/dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sendPrep, err := Sendmsg(fds[0], []byte("fd"), syscall.UnixRights(int(f.Fd())), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, oob := make([]byte, 2), make([]byte, syscall.CmsgSpace(4))
+	recvPrep, err := Recvmsg(fds[1], p, oob, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{sendPrep, recvPrep}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+
+	info := requests.Requests()[1].ReturnValue1().(*RecvmsgResult)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:info.Oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("received %d control messages, want 1", len(msgs))
+	}
+	rights, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rights) != 1 {
+		t.Fatalf("received %d fds, want 1", len(rights))
+	}
+	syscall.Close(rights[0])
+}
+
+func TestAccept4(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, fd := listenTCP(t)
+	defer ln.Close()
+
+	request, err := iour.SubmitRequest(Accept4(fd, syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	<-request.Done()
+	connFd, err := request.ReturnFd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(connFd)
+
+	sa, ok := request.ReturnValue1().(*syscall.SockaddrInet4)
+	if !ok {
+		t.Fatalf("accepted sockaddr is %T, want *syscall.SockaddrInet4", request.ReturnValue1())
+	}
+	if localAddr := conn.LocalAddr().(*net.TCPAddr); sa.Port != localAddr.Port {
+		t.Fatalf("accepted port is %d, want %d", sa.Port, localAddr.Port)
+	}
+	if addr := AcceptedAddr(request); addr == nil || addr.String() != conn.LocalAddr().String() {
+		t.Fatalf("accepted address is %v, want %v", addr, conn.LocalAddr())
+	}
+
+	flags, err := unix.FcntlInt(uintptr(connFd), syscall.F_GETFL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&syscall.O_NONBLOCK == 0 {
+		t.Fatal("accepted fd is not nonblocking")
+	}
+}
+
+func TestSendmsgRecvmsgBuffers(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	sendPrep, err := SendmsgBuffers(fds[0], [][]byte{[]byte("io with "), []byte("iouring")}, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := [][]byte{make([]byte, 3), make([]byte, 5)}
+	recvPrep, err := RecvmsgBuffers(fds[1], bs, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{sendPrep, recvPrep}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+
+	recv := requests.Requests()[1]
+	if n, _ := recv.ReturnInt(); n != 8 {
+		t.Fatalf("received %d bytes, want 8", n)
+	}
+	if got := string(bytes.Join(bs, nil)); got != "io with " {
+		t.Fatalf("received %q, want %q", got, "io with ")
+	}
+	if info := recv.ReturnValue1().(*RecvmsgResult); info.Recvflags&syscall.MSG_TRUNC == 0 {
+		t.Fatal("MSG_TRUNC is not set on the truncated message")
+	}
+}
+
+func TestCloseRequest(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fd, err := syscall.Open("/dev/zero", syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.FileRegister().RegisterFile(int32(fd)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 8)
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{Read(fd, buf), Close(fd)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+
+	if _, ok := iour.FileRegister().GetFileIndex(int32(fd)); ok {
+		t.Fatal("closed fd is still registered")
+	}
+
+	request, err := iour.SubmitRequest(Read(fd, buf), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if err := request.Err(); err != syscall.EBADF {
+		t.Fatalf("read closed fd error is %v, want %v", err, syscall.EBADF)
+	}
+}
+
+func TestOpenat2ReadClose(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	prep, err := Openat2(unix.AT_FDCWD, "/dev/zero", &unix.OpenHow{Flags: unix.O_RDONLY | unix.O_CLOEXEC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequest(prep, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	fd, err := request.ReturnFd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := []byte{1}
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{Read(fd, buf), Close(fd)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+	if buf[0] != 0 {
+		t.Fatalf("read %d from /dev/zero", buf[0])
+	}
+}
+
+func TestOpenat2ResolveBeneath(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	dir, err := ioutil.TempDir("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root, err := os.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	how := &unix.OpenHow{Flags: unix.O_RDONLY | unix.O_CLOEXEC, Resolve: unix.RESOLVE_BENEATH}
+	prep, err := Openat2(int(root.Fd()), "../", how)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequest(prep, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-request.Done()
+	if _, err := request.ReturnFd(); err != syscall.EXDEV {
+		t.Fatalf("escape the root directory: %v, want %v", err, syscall.EXDEV)
+	}
+}
+
+func TestStatx(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.WriteString("io with iouring"); err != nil {
+		t.Fatal(err)
+	}
+
+	pathPrep, err := Statx(unix.AT_FDCWD, f.Name(), 0, unix.STATX_SIZE, &unix.Statx_t{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fdPrep, err := Statx(int(f.Fd()), "", unix.AT_EMPTY_PATH, unix.STATX_SIZE, &unix.Statx_t{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := iour.SubmitRequests([]PrepRequest{pathPrep, fdPrep}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+
+	for _, request := range requests.Requests() {
+		if err := request.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if stat := request.ReturnValue0().(*unix.Statx_t); stat.Size != 15 {
+			t.Fatalf("statx size is %d, want 15", stat.Size)
+		}
+	}
+}
+
+func TestSpliceTee(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	src, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	if _, err := src.WriteString("io with iouring"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	r1, w1, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close()
+	defer w1.Close()
+	r2, w2, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	defer w2.Close()
+
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		Splice(int(src.Fd()), 0, int(w1.Fd()), -1, 15, 0),
+		Tee(int(r1.Fd()), int(w2.Fd()), 15, 0),
+		Splice(int(r1.Fd()), -1, int(dst.Fd()), 0, 15, 0),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	for _, request := range requests.Requests() {
+		n, err := request.ReturnInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 15 {
+			t.Fatalf("opcode %d moved %d bytes, want 15", request.Opcode(), n)
+		}
+	}
+
+	buf := make([]byte, 15)
+	if _, err := dst.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "io with iouring" {
+		t.Fatalf("spliced %q, want %q", buf, "io with iouring")
+	}
+
+	if _, err := io.ReadFull(r2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "io with iouring" {
+		t.Fatalf("teed %q, want %q", buf, "io with iouring")
+	}
+}
+
+func TestSpliceFixedFile(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	src, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	if _, err := src.WriteString("io with iouring"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if err := iour.RegisterFiles([]*os.File{w, src}); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := iour.SubmitRequestSync(Splice(int(src.Fd()), 0, int(w.Fd()), -1, 15, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := request.ReturnInt(); err != nil || n != 15 {
+		t.Fatalf("splice the registered files: %d, %v", n, err)
+	}
+
+	buf := make([]byte, 15)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "io with iouring" {
+		t.Fatalf("spliced %q, want %q", buf, "io with iouring")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		Send(fds[0], []byte("io with iouring"), 0),
+		Shutdown(fds[0], syscall.SHUT_WR),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+
+	buf := make([]byte, 32)
+	n, err := syscall.Read(fds[1], buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "io with iouring" {
+		t.Fatalf("read %q, want %q", buf[:n], "io with iouring")
+	}
+	if n, err := syscall.Read(fds[1], buf); err != nil || n != 0 {
+		t.Fatalf("read after shutdown: %d, %v, want EOF", n, err)
+	}
+
+	// the read side is still open
+	if _, err := syscall.Write(fds[1], []byte("io")); err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequestSync(Recv(fds[0], buf, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := request.ReturnInt(); err != nil || n != 2 {
+		t.Fatalf("recv after shutdown the write side: %d, %v", n, err)
+	}
+}
+
+func TestSendZC(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ln, _ := listenTCP(t)
+	defer ln.Close()
+
+	conn, err := net.DialTCP("tcp4", nil, ln.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	peer, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fd int
+	if err := rawConn.Control(func(sysfd uintptr) { fd = int(sysfd) }); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Result, 2)
+	request, err := iour.SubmitRequest(SendZC(fd, []byte("io with iouring"), 0), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := <-ch
+	if sent.Flags()&iouring_syscall.IORING_CQE_F_MORE == 0 {
+		t.Fatalf("IORING_CQE_F_MORE is not set on the send result: %v", sent.Err())
+	}
+	if n, err := sent.ReturnInt(); err != nil || n != 15 {
+		t.Fatalf("send: %d, %v, want 15 bytes sent", n, err)
+	}
+
+	notif := <-ch
+	if notif != request {
+		t.Fatal("notification is not the submitted request")
+	}
+	if notif.Flags()&iouring_syscall.IORING_CQE_F_NOTIF == 0 {
+		t.Fatal("IORING_CQE_F_NOTIF is not set on the notification")
+	}
+	if n, err := notif.ReturnInt(); err != nil || n != 15 {
+		t.Fatalf("completed send: %d, %v, want 15 bytes sent", n, err)
+	}
+
+	buf := make([]byte, 15)
+	if _, err := io.ReadFull(peer, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "io with iouring" {
+		t.Fatalf("received %q, want %q", buf, "io with iouring")
+	}
+}
+
+func TestFallocate(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	statx := func() *unix.Statx_t {
+		t.Helper()
+		prep, err := Statx(int(f.Fd()), "", unix.AT_EMPTY_PATH, unix.STATX_SIZE|unix.STATX_BLOCKS, &unix.Statx_t{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := iour.SubmitRequestSync(prep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := request.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return request.ReturnValue0().(*unix.Statx_t)
+	}
+
+	fallocate := func(mode uint32, off, length int64) {
+		t.Helper()
+		request, err := iour.SubmitRequestSync(Fallocate(int(f.Fd()), mode, off, length))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := request.Err(); err != nil {
+			if err == syscall.EOPNOTSUPP {
+				t.Skip("fallocate mode is not supported by the file system")
+			}
+			t.Fatal(err)
+		}
+	}
+
+	const length = 1 << 20
+
+	// the blocks are reserved without changing the size
+	fallocate(unix.FALLOC_FL_KEEP_SIZE, 0, length)
+	stat := statx()
+	if stat.Size != 0 {
+		t.Fatalf("size is %d after fallocate with FALLOC_FL_KEEP_SIZE, want 0", stat.Size)
+	}
+	if stat.Blocks*512 < length {
+		t.Fatalf("%d blocks are allocated, want at least %d bytes", stat.Blocks, length)
+	}
+
+	fallocate(0, 0, length)
+	if stat := statx(); stat.Size != length {
+		t.Fatalf("size is %d after fallocate, want %d", stat.Size, length)
+	}
+
+	// punch a hole in the second half
+	fallocate(unix.FALLOC_FL_KEEP_SIZE|unix.FALLOC_FL_PUNCH_HOLE, length/2, length/2)
+	stat = statx()
+	if stat.Size != length {
+		t.Fatalf("size is %d after punching a hole, want %d", stat.Size, length)
+	}
+	if stat.Blocks*512 > length/2 {
+		t.Fatalf("%d blocks are allocated after punching a hole, want at most %d bytes", stat.Blocks, length/2)
+	}
+}
+
+func TestFadviseMadvise(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.Write(make([]byte, 1<<16)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := syscall.Mmap(-1, 0, os.Getpagesize(), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(b)
+
+	// the prefetch hint is linked before the read
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		Fadvise(int(f.Fd()), 0, 1<<16, unix.FADV_WILLNEED),
+		Madvise(b, unix.MADV_WILLNEED),
+		Pread(int(f.Fd()), make([]byte, 1<<16), 0),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+
+	for _, request := range requests.Requests()[:2] {
+		if res, err := request.ReturnValue(); res != 0 || err != nil {
+			t.Fatalf("advice of opcode %d returns %d, %v, want 0", request.Opcode(), res, err)
+		}
+	}
+	if n, err := requests.Requests()[2].ReturnInt(); err != nil || n != 1<<16 {
+		t.Fatalf("read returns %d, %v, want %d", n, err, 1<<16)
+	}
+}
+
+func TestMkdiratRenameatUnlinkat(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	dir, err := ioutil.TempDir("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dirFile, err := os.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dirFile.Close()
+	dirFd := int(dirFile.Fd())
+
+	mkdir, err := Mkdirat(dirFd, "a", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rename, err := Renameat(dirFd, "a", dirFd, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the directory is renamed after it's created
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{mkdir, rename}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); len(errResults) != 0 {
+		t.Fatal(errResults[0].Err())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Fatalf("renamed directory still exists: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "b")); err != nil || !info.IsDir() {
+		t.Fatalf("directory is not renamed: %v", err)
+	}
+
+	unlink, err := Unlinkat(dirFd, "b", unix.AT_REMOVEDIR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := iour.SubmitRequestSync(unlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Fatalf("directory is not removed: %v", err)
+	}
+
+	// the missing path is reported by the result
+	unlink, err = Unlinkat(dirFd, "b", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err = iour.SubmitRequestSync(unlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != syscall.ENOENT {
+		t.Fatalf("unlink missing path: %v, want %v", err, syscall.ENOENT)
+	}
+}
+
+func TestSymlinkatLinkat(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	dir, err := ioutil.TempDir("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "target")
+	if err := ioutil.WriteFile(target, []byte("iouring"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	symlink, err := Symlinkat(target, unix.AT_FDCWD, filepath.Join(dir, "symlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := Linkat(unix.AT_FDCWD, target, unix.AT_FDCWD, filepath.Join(dir, "link"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, err := iour.SubmitRequests([]PrepRequest{symlink, link}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); len(errResults) != 0 {
+		t.Fatal(errResults[0].Err())
+	}
+
+	statx := func(path string, flags uint32) *unix.Statx_t {
+		t.Helper()
+		prep, err := Statx(unix.AT_FDCWD, path, flags, unix.STATX_TYPE|unix.STATX_INO|unix.STATX_NLINK, &unix.Statx_t{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := iour.SubmitRequestSync(prep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := request.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return request.ReturnValue0().(*unix.Statx_t)
+	}
+
+	targetStat := statx(target, 0)
+	if targetStat.Nlink != 2 {
+		t.Fatalf("target has %d links, want 2", targetStat.Nlink)
+	}
+	if stat := statx(filepath.Join(dir, "link"), 0); stat.Ino != targetStat.Ino {
+		t.Fatalf("hard link inode is %d, want %d", stat.Ino, targetStat.Ino)
+	}
+
+	if stat := statx(filepath.Join(dir, "symlink"), unix.AT_SYMLINK_NOFOLLOW); stat.Mode&unix.S_IFMT != unix.S_IFLNK {
+		t.Fatalf("symlink mode is %o, want a symbolic link", stat.Mode)
+	}
+	if stat := statx(filepath.Join(dir, "symlink"), 0); stat.Ino != targetStat.Ino {
+		t.Fatalf("symlink resolves to inode %d, want %d", stat.Ino, targetStat.Ino)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	submit := func(prep PrepRequest, err error) Request {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := iour.SubmitRequestSync(prep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := request.Err(); err != nil {
+			if err == syscall.EOPNOTSUPP {
+				t.Skip("extended attributes are not supported by the file system")
+			}
+			t.Fatal(err)
+		}
+		return request
+	}
+
+	submit(Fsetxattr(int(f.Fd()), "user.fd", []byte("iouring"), unix.XATTR_CREATE))
+	submit(Setxattr(f.Name(), "user.path", []byte("iouring-go"), 0))
+
+	value := make([]byte, 64)
+	if n, _ := submit(Fgetxattr(int(f.Fd()), "user.path", value)).ReturnInt(); string(value[:n]) != "iouring-go" {
+		t.Fatalf("user.path is %q, want %q", value[:n], "iouring-go")
+	}
+	if n, _ := submit(Getxattr(f.Name(), "user.fd", value)).ReturnInt(); string(value[:n]) != "iouring" {
+		t.Fatalf("user.fd is %q, want %q", value[:n], "iouring")
+	}
+
+	// the size of the value is returned for the empty value
+	if n, _ := submit(Getxattr(f.Name(), "user.path", nil)).ReturnInt(); n != len("iouring-go") {
+		t.Fatalf("size of user.path is %d, want %d", n, len("iouring-go"))
+	}
+}
+
+func TestWithDrain(t *testing.T) {
+	iour, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	ch := make(chan Result, 4)
+	requests := []PrepRequest{Timeout(50 * time.Millisecond).WithInfo("timeout")}
+	// the drained request starts the link after the timeout is completed
+	requests = append(requests, Link(Nop().WithDrain().WithInfo("drain"), Nop().WithInfo("linked"))...)
+	if _, err := iour.SubmitRequests(requests, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range []string{"timeout", "drain", "linked"} {
+		select {
+		case result := <-ch:
+			if result.GetRequestInfo() != info {
+				t.Fatalf("%v is completed, want %s", result.GetRequestInfo(), info)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s is not completed", info)
+		}
+	}
+}
+
+func TestWithAsync(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	sqe := &iouring_syscall.SubmissionQueueEntry64{}
+	Nop().WithAsync()(sqe, makeUserData(iour, nil))
+	if sqe.Flags()&iouring_syscall.IOSQE_FLAGS_ASYNC == 0 {
+		t.Fatal("IOSQE_ASYNC is not set")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// the blocking read is issued by the worker thread
+	request, err := iour.SubmitRequest(Read(int(r.Fd()), make([]byte, 5)).WithAsync(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("async")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-request.Done():
+	case <-time.After(time.Second):
+		t.Fatal("async read is not completed")
+	}
+	if n, err := request.ReturnInt(); err != nil || n != 5 {
+		t.Fatalf("async read returns %d, %v, want 5", n, err)
+	}
+}
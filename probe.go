@@ -1,3 +1,48 @@
 // +build linux
 
 package iouring
+
+import (
+	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// Probe contains the operations supported by the running kernel
+type Probe struct {
+	probe iouring_syscall.IOURingProbe
+}
+
+// Probe returns the operations supported by the running kernel,
+// IORING_REGISTER_PROBE is available since 5.6
+func (iour *IOURing) Probe() (*Probe, error) {
+	probe := &Probe{}
+	if err := iouring_syscall.IOURingRegister(
+		iour.fd,
+		iouring_syscall.IORING_REGISTER_PROBE,
+		unsafe.Pointer(&probe.probe),
+		uint32(len(probe.probe.Ops)),
+	); err != nil {
+		return nil, err
+	}
+	return probe, nil
+}
+
+// LastOp returns the last operation known by the running kernel
+func (probe *Probe) LastOp() uint8 {
+	return probe.probe.LastOp
+}
+
+// Supports returns whether the operation op is supported by the running kernel
+func (probe *Probe) Supports(op uint8) bool {
+	if op > probe.probe.LastOp || op >= probe.probe.OpsLen {
+		return false
+	}
+	return probe.probe.Ops[op].Flags&iouring_syscall.IO_URING_OP_SUPPORTED != 0
+}
+
+// SupportsFeature returns whether the feature, one of the IORING_FEAT_*,
+// is reported by the running kernel when the io_uring instance is setup
+func (iour *IOURing) SupportsFeature(feature uint32) bool {
+	return iour.Features&feature == feature
+}
@@ -0,0 +1,52 @@
+// +build linux
+
+package iouring
+
+import (
+	"testing"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+func TestProbe(t *testing.T) {
+	iour, err := New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	probe, err := iour.Probe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if probe.LastOp() == 0 {
+		t.Fatal("no operation is known by the kernel")
+	}
+	for _, op := range []uint8{iouring_syscall.IORING_OP_NOP, iouring_syscall.IORING_OP_READV, iouring_syscall.IORING_OP_TIMEOUT} {
+		if !probe.Supports(op) {
+			t.Fatalf("operation %d is not supported", op)
+		}
+	}
+	if probe.Supports(probe.LastOp() + 1) {
+		t.Fatalf("operation %d after the last operation is supported", probe.LastOp()+1)
+	}
+}
+
+func TestSupportsFeature(t *testing.T) {
+	iour, err := New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	// SINGLE_MMAP and NODROP are reported since 5.4
+	for _, feature := range []uint32{iouring_syscall.IORING_FEAT_SINGLE_MMAP, iouring_syscall.IORING_FEAT_NODROP} {
+		if !iour.SupportsFeature(feature) {
+			t.Fatalf("feature %#x is not supported, features %#x", feature, iour.Features)
+		}
+	}
+	if iour.SupportsFeature(1 << 31) {
+		t.Fatal("unknown feature is supported")
+	}
+}
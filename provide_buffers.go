@@ -0,0 +1,92 @@
+// +build linux
+
+package iouring
+
+import (
+	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// ProvideBuffers provide b as count buffers of equal size to the buffer group groupID,
+// the buffer ids start from startBID. The buffers are selected by the kernel
+// for the requests with the buffer group, such as RecvSelect,
+// b must be kept alive until the buffers are used or removed
+func ProvideBuffers(b []byte, count int, groupID uint16, startBID uint16) PrepRequest {
+	var bp unsafe.Pointer
+	if len(b) > 0 {
+		bp = unsafe.Pointer(&b[0])
+	}
+
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+		userData.SetRequestBuffer(b, nil)
+
+		var size int
+		if count > 0 {
+			size = len(b) / count
+		}
+		sqe.PrepOperation(
+			iouring_syscall.IORING_OP_PROVIDE_BUFFERS,
+			int32(count),
+			uint64(uintptr(bp)),
+			uint32(size),
+			uint64(startBID),
+		)
+		sqe.SetBufGroup(groupID)
+	}
+}
+
+// RemoveBuffers remove up to count unused buffers from the buffer group groupID,
+// the result returns the number of buffers removed by ReturnInt
+func RemoveBuffers(count int, groupID uint16) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_REMOVE_BUFFERS, int32(count), 0, 0, 0)
+		sqe.SetBufGroup(groupID)
+	}
+}
+
+// RecvSelect receive from the socket into a buffer selected by the kernel from the buffer group groupID,
+// the result returns the number of bytes received by ReturnInt and the selected buffer id by BufferID
+func RecvSelect(sockfd int, groupID uint16, flags int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_RECV, int32(sockfd), 0, 0, 0)
+		sqe.SetOpFlags(uint32(flags))
+		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_BUFFER_SELECT)
+		sqe.SetBufGroup(groupID)
+	}
+}
+
+// RecvMultishot receive from the socket repeatedly into buffers selected by the kernel
+// from the buffer group groupID, a result is notified via channel for each receive
+// with the number of bytes received by ReturnInt and the selected buffer id by BufferID.
+// The request is completed when the result flags have no IORING_CQE_F_MORE,
+// such as the buffer group is exhausted or the connection is closed.
+// IORING_RECV_MULTISHOT is available since 6.0
+func RecvMultishot(sockfd int, groupID uint16, flags int) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_RECV, int32(sockfd), 0, 0, 0)
+		sqe.SetOpFlags(uint32(flags))
+		sqe.SetIoprio(iouring_syscall.IORING_RECV_MULTISHOT)
+		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_BUFFER_SELECT)
+		sqe.SetBufGroup(groupID)
+	}
+}
+
+// ReadSelect read from fd at offset into a buffer selected by the kernel from the buffer group groupID,
+// the result returns the number of bytes read by ReturnInt and the selected buffer id by BufferID
+func ReadSelect(fd int, groupID uint16, offset uint64) PrepRequest {
+	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.request.resolver = fdResolver
+
+		sqe.PrepOperation(iouring_syscall.IORING_OP_READ, int32(fd), 0, 0, offset)
+		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_BUFFER_SELECT)
+		sqe.SetBufGroup(groupID)
+	}
+}
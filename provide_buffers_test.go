@@ -0,0 +1,162 @@
+// +build linux
+
+package iouring
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRecvSelect(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	const size = 16
+	buffers := make([]byte, 4*size)
+	request, err := iour.SubmitRequestSync(ProvideBuffers(buffers, 4, 1, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := syscall.Write(fds[1], []byte("io with iouring")); err != nil {
+		t.Fatal(err)
+	}
+	request, err = iour.SubmitRequestSync(RecvSelect(fds[0], 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := request.ReturnInt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, ok := request.BufferID()
+	if !ok {
+		t.Fatal("no buffer is selected")
+	}
+	if id < 10 || id >= 14 {
+		t.Fatalf("selected buffer id %d, want in [10, 14)", id)
+	}
+	buffer := buffers[int(id-10)*size:]
+	if string(buffer[:n]) != "io with iouring" {
+		t.Fatalf("received %q, want %q", buffer[:n], "io with iouring")
+	}
+
+	request, err = iour.SubmitRequestSync(RemoveBuffers(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := request.ReturnInt(); err != nil || n != 3 {
+		t.Fatalf("remove buffers: %d, %v, want 3 removed", n, err)
+	}
+
+	// no buffer is left in the group
+	if _, err := syscall.Write(fds[1], []byte("io with iouring")); err != nil {
+		t.Fatal(err)
+	}
+	request, err = iour.SubmitRequestSync(RecvSelect(fds[0], 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request.ReturnInt(); err != syscall.ENOBUFS {
+		t.Fatalf("receive without buffers: %v, want %v", err, syscall.ENOBUFS)
+	}
+}
+
+func TestProvideBuffersWithRegisteredFile(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := os.Open("/dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := iour.RegisterFile(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// the count of the buffers is equal to the registered fd
+	count := int(f.Fd())
+	buffers := make([]byte, count*8)
+	request, err := iour.SubmitRequestSync(ProvideBuffers(buffers, count, 3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fd := request.Fd(); fd != -1 {
+		t.Fatalf("fd of provide buffers is %d, want -1", fd)
+	}
+
+	request, err = iour.SubmitRequestSync(RemoveBuffers(count, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := request.ReturnInt(); err != nil || n != count {
+		t.Fatalf("remove buffers: %d, %v, want %d removed", n, err, count)
+	}
+}
+
+func TestReadSelect(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := ioutil.TempFile("", "iouring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString("io with iouring"); err != nil {
+		t.Fatal(err)
+	}
+
+	buffers := make([]byte, 2*4)
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		ProvideBuffers(buffers, 2, 2, 0),
+		ReadSelect(int(f.Fd()), 2, 3),
+		ReadSelect(int(f.Fd()), 2, 8),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+	if errResults := requests.ErrResults(); errResults != nil {
+		t.Fatal(errResults[0].Err())
+	}
+
+	var read []string
+	for _, request := range requests.Requests()[1:] {
+		n, _ := request.ReturnInt()
+		id, ok := request.BufferID()
+		if !ok || id > 1 {
+			t.Fatalf("selected buffer id %d(%t), want 0 or 1", id, ok)
+		}
+		read = append(read, string(buffers[id*4:int(id*4)+n]))
+	}
+	if read[0] != "with" || read[1] != "iour" {
+		t.Fatalf("read %q, want %q", read, []string{"with", "iour"})
+	}
+}
@@ -19,6 +19,9 @@ type RequestCallback func(result Result) error
 type Request interface {
 	Result
 
+	// ID returns the request id, the user data of the submission queue entry,
+	// it's used to remove the poll or timeout request by PollRemove or RemoveTimeout
+	ID() uint64
 	Cancel() (Request, error)
 	Done() <-chan struct{}
 
@@ -27,21 +30,42 @@ type Request interface {
 	SetResult(r0, r1 interface{}, err error) error
 }
 
+// Result is the outcome of a completed request, the negative result of the
+// completion event is translated into a syscall.Errno by the resolver of the request
 type Result interface {
+	// Fd returns the fd of the request
 	Fd() int
+	// Opcode returns the IORING_OP_* of the request
 	Opcode() uint8
+	// Flags of the completion event, eg. IORING_CQE_F_MORE is set
+	// if the multishot request will post more completion events
+	Flags() uint32
+	// BufferID returns the id of the buffer selected by the kernel,
+	// ok is false if no buffer is selected
+	BufferID() (id uint16, ok bool)
 	GetRequestBuffer() (b0, b1 []byte)
 	GetRequestBuffers() [][]byte
 	GetRequestInfo() interface{}
 	FreeRequestBuffer()
 
+	// Err returns the error of the request, the syscall.Errno of the negative result,
+	// or ErrRequestCanceled if the request is canceled
 	Err() error
+	// ReturnValue0 returns the first value of the resolver, such as the fd of Accept,
+	// the number of bytes of Read and Write, or the *unix.Statx_t of Statx
 	ReturnValue0() interface{}
+	// ReturnValue1 returns the second value of the resolver, such as the peer syscall.Sockaddr of Accept
 	ReturnValue1() interface{}
+	// ReturnExtra1 and ReturnExtra2 return the extra fields of the 32-byte completion event
 	ReturnExtra1() uint64
 	ReturnExtra2() uint64
+	// ReturnFd returns the fd of Accept, Openat and the other requests creating an fd
 	ReturnFd() (int, error)
+	// ReturnInt returns the int result of the request, such as the number of bytes of Read and Write
 	ReturnInt() (int, error)
+	// ReturnValue returns the raw result of the completion event regardless of the resolver,
+	// err is the syscall.Errno of a negative res
+	ReturnValue() (res int, err error)
 
 	Callback() error
 }
@@ -54,6 +78,7 @@ type request struct {
 	id     uint64
 	opcode uint8
 	res    int32
+	flags  uint32
 
 	once      sync.Once
 	resolving bool
@@ -98,11 +123,46 @@ func (req *request) resolve() {
 	})
 }
 
+// clone make a new request for the completion event of the multishot request,
+// the original request is completed by the final completion event
+func (req *request) clone() *request {
+	return &request{
+		id:          req.id,
+		opcode:      req.opcode,
+		resolver:    req.resolver,
+		callback:    req.callback,
+		fd:          req.fd,
+		b0:          req.b0,
+		b1:          req.b1,
+		bs:          req.bs,
+		requestInfo: req.requestInfo,
+		done:        make(chan struct{}),
+	}
+}
+
 func (req *request) complate(cqe iouring_syscall.CompletionQueueEvent) {
-	req.res = cqe.Result()
+	// the notification of the zero-copy send has no result,
+	// the result of the send posted before is kept
+	if cqe.Flags()&iouring_syscall.IORING_CQE_F_NOTIF == 0 {
+		req.res = cqe.Result()
+	}
+	req.flags = cqe.Flags()
 	req.ext1 = cqe.Extra1()
 	req.ext2 = cqe.Extra2()
-	req.iour = nil
+	close(req.done)
+
+	if req.set != nil {
+		req.set.complateOne()
+		req.set = nil
+	}
+}
+
+// terminate completes the request without a completion event,
+// it is used to fail the in-flight requests when IOURing is closed
+func (req *request) terminate(err error) {
+	req.res = -int32(syscall.ECANCELED)
+	req.err = err
+	req.resolver = nil
 	close(req.done)
 
 	if req.set != nil {
@@ -141,6 +201,11 @@ func (req *request) Cancel() (Request, error) {
 	return req.iour.submitCancel(req.id)
 }
 
+// ID returns the request id, the user data of the submission queue entry
+func (req *request) ID() uint64 {
+	return req.id
+}
+
 func (req *request) Done() <-chan struct{} {
 	return req.done
 }
@@ -149,6 +214,17 @@ func (req *request) Opcode() uint8 {
 	return req.opcode
 }
 
+func (req *request) Flags() uint32 {
+	return req.flags
+}
+
+func (req *request) BufferID() (uint16, bool) {
+	if req.flags&iouring_syscall.IORING_CQE_F_BUFFER == 0 {
+		return 0, false
+	}
+	return uint16(req.flags >> iouring_syscall.IORING_CQE_BUFFER_SHIFT), true
+}
+
 func (req *request) Fd() int {
 	return req.fd
 }
@@ -207,6 +283,17 @@ func (req *request) ReturnInt() (int, error) {
 	return fd, nil
 }
 
+func (req *request) ReturnValue() (int, error) {
+	if !req.isDone() {
+		return 0, ErrRequestNotCompleted
+	}
+
+	if req.res < 0 {
+		return int(req.res), syscall.Errno(-req.res)
+	}
+	return int(req.res), nil
+}
+
 func (req *request) FreeRequestBuffer() {
 	req.b0 = nil
 	req.b1 = nil
@@ -245,13 +332,18 @@ var _ RequestSet = &requestSet{}
 type requestSet struct {
 	requests []Request
 
+	// total is the number of the requests to be completed,
+	// it's less than len(requests) after truncate
+	total     int32
 	complates int32
+	doneOnce  sync.Once
 	done      chan struct{}
 }
 
 func newRequestSet(userData []*UserData) *requestSet {
 	set := &requestSet{
 		requests: make([]Request, len(userData)),
+		total:    int32(len(userData)),
 		done:     make(chan struct{}),
 	}
 
@@ -263,8 +355,18 @@ func newRequestSet(userData []*UserData) *requestSet {
 }
 
 func (set *requestSet) complateOne() {
-	if atomic.AddInt32(&set.complates, 1) == int32(len(set.requests)) {
-		close(set.done)
+	if atomic.AddInt32(&set.complates, 1) == atomic.LoadInt32(&set.total) {
+		set.doneOnce.Do(func() { close(set.done) })
+	}
+}
+
+// truncate keep the first n requests of the set, the set is done when they are completed,
+// the requests may be completed concurrently
+func (set *requestSet) truncate(n int) {
+	set.requests = set.requests[:n]
+	atomic.StoreInt32(&set.total, int32(n))
+	if atomic.LoadInt32(&set.complates) == int32(n) {
+		set.doneOnce.Do(func() { close(set.done) })
 	}
 }
 
@@ -296,6 +398,11 @@ func errResolver(req Request) {
 		if result.err == syscall.ECANCELED {
 			// request is canceled
 			result.err = ErrRequestCanceled
+		} else if result.err == syscall.EOPNOTSUPP && isReadWriteOp(result.opcode) &&
+			result.iour != nil && result.iour.Flags&iouring_syscall.IORING_SETUP_IOPOLL != 0 {
+			// the reads and writes of IOPOLL ring fail with EOPNOTSUPP
+			// if the file is not opened with O_DIRECT
+			result.err = ErrNotDirectIO
 		}
 	}
 }
@@ -345,6 +452,20 @@ func removeTimeoutResolver(req Request) {
 	// result.res value is 0
 }
 
+func removePollResolver(req Request) {
+	result := req.(*request)
+	if errResolver(result); result.err != nil {
+		switch result.err {
+		case syscall.EALREADY:
+			// poll request was found but it's already completing
+			result.err = ErrRequestCompleted
+		case syscall.ENOENT:
+			// poll request not found
+			result.err = ErrRequestNotFound
+		}
+	}
+}
+
 func cancelResolver(req Request) {
 	result := req.(*request)
 	if errResolver(result); result.err != nil {
@@ -0,0 +1,74 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"errors"
+	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// Restriction allows a register operation, a request operation or the sqe flags
+// on the ring, the other operations fail with EACCES once restrictions are registered
+type Restriction struct {
+	opcode uint16
+	arg    uint8
+}
+
+// AllowRegisterOp allow the register operation op, such as IORING_REGISTER_FILES
+func AllowRegisterOp(op uint8) Restriction {
+	return Restriction{opcode: iouring_syscall.IORING_RESTRICTION_REGISTER_OP, arg: op}
+}
+
+// AllowOp allow the request operation op, such as IORING_OP_READ
+func AllowOp(op uint8) Restriction {
+	return Restriction{opcode: iouring_syscall.IORING_RESTRICTION_SQE_OP, arg: op}
+}
+
+// AllowSQEFlags allow the sqe flags, such as IOSQE_FLAGS_FIXED_FILE
+func AllowSQEFlags(flags uint8) Restriction {
+	return Restriction{opcode: iouring_syscall.IORING_RESTRICTION_SQE_FLAGS_ALLOWED, arg: flags}
+}
+
+// RequireSQEFlags require the sqe flags on every request, such as IOSQE_FLAGS_FIXED_FILE
+func RequireSQEFlags(flags uint8) Restriction {
+	return Restriction{opcode: iouring_syscall.IORING_RESTRICTION_SQE_FLAGS_REQUIRED, arg: flags}
+}
+
+// RegisterRestrictions register the restrictions of the ring, it can only be called
+// once on the ring created by WithDisableRing and before Enable.
+// IORING_REGISTER_RESTRICTIONS is available since 5.10
+func (iour *IOURing) RegisterRestrictions(restrictions []Restriction) error {
+	if len(restrictions) == 0 {
+		return errors.New("restrictions is empty")
+	}
+
+	rs := make([]iouring_syscall.IOURingRestriction, len(restrictions))
+	for i, r := range restrictions {
+		rs[i].Opcode = r.opcode
+		rs[i].Arg = r.arg
+	}
+	return iouring_syscall.IOURingRegister(
+		iour.fd,
+		iouring_syscall.IORING_REGISTER_RESTRICTIONS,
+		unsafe.Pointer(&rs[0]),
+		uint32(len(rs)),
+	)
+}
+
+// Enable enable the ring created by WithDisableRing, the requests can be submitted
+// and the restrictions are enforced after it's enabled, the requests submitted
+// before fail with ErrIOURingDisabled.
+// IORING_REGISTER_ENABLE_RINGS is available since 5.10
+func (iour *IOURing) Enable() error {
+	iour.submitLock.Lock()
+	defer iour.submitLock.Unlock()
+
+	if err := iouring_syscall.IOURingRegister(iour.fd, iouring_syscall.IORING_REGISTER_ENABLE_RINGS, nil, 0); err != nil {
+		return err
+	}
+	iour.disabled = false
+	return nil
+}
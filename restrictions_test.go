@@ -0,0 +1,106 @@
+// +build linux
+
+package iouring
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+func TestRegisterRestrictions(t *testing.T) {
+	iour, err := New(4, WithDisableRing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if err := iour.RegisterRestrictions([]Restriction{
+		AllowOp(iouring_syscall.IORING_OP_NOP),
+		AllowOp(iouring_syscall.IORING_OP_READ),
+		AllowSQEFlags(iouring_syscall.IOSQE_FLAGS_FIXED_FILE),
+		RequireSQEFlags(iouring_syscall.IOSQE_FLAGS_FIXED_FILE),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iour.SubmitRequest(Nop(), nil); err != ErrIOURingDisabled {
+		t.Fatalf("submit request on the disabled ring: %v, want %v", err, ErrIOURingDisabled)
+	}
+	if _, err := iour.SubmitRequests([]PrepRequest{Nop()}, nil); err != ErrIOURingDisabled {
+		t.Fatalf("submit requests on the disabled ring: %v, want %v", err, ErrIOURingDisabled)
+	}
+	if err := iour.Enable(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open("/dev/zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	submit := func(prep PrepRequest) error {
+		t.Helper()
+		request, err := iour.SubmitRequestSync(prep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return request.Err()
+	}
+
+	// the fixed file flag is required
+	if err := submit(Read(int(f.Fd()), make([]byte, 1))); err != syscall.EACCES {
+		t.Fatalf("read of the unregistered file: %v, want %v", err, syscall.EACCES)
+	}
+
+	// the register operations are not allowed after enabling the restricted ring
+	if err := iour.RegisterFile(f); err == nil {
+		t.Fatal("register file on the restricted ring returns nil error")
+	}
+}
+
+func TestRegisterRestrictionsFixedFile(t *testing.T) {
+	iour, err := New(4, WithDisableRing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	f, err := os.Open("/dev/zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the files are registered before the ring is enabled
+	if err := iour.RegisterFile(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.RegisterRestrictions([]Restriction{
+		AllowOp(iouring_syscall.IORING_OP_READ),
+		AllowSQEFlags(iouring_syscall.IOSQE_FLAGS_FIXED_FILE),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := iour.Enable(); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := iour.SubmitRequestSync(Read(int(f.Fd()), make([]byte, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request.ReturnInt(); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err = iour.SubmitRequestSync(Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Err(); err != syscall.EACCES {
+		t.Fatalf("nop on the restricted ring: %v, want %v", err, syscall.EACCES)
+	}
+}
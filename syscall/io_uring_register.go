@@ -22,6 +22,17 @@ const (
 	IORING_UNREGISTER_PERSONALITY
 	IORING_REGISTER_RESTRICTIONS
 	IORING_REGISTER_ENABLE_RINGS
+	IORING_REGISTER_FILES2
+	IORING_REGISTER_FILES_UPDATE2
+	IORING_REGISTER_BUFFERS2
+	IORING_REGISTER_BUFFERS_UPDATE
+	IORING_REGISTER_IOWQ_AFF
+	IORING_UNREGISTER_IOWQ_AFF
+	IORING_REGISTER_IOWQ_MAX_WORKERS
+	IORING_REGISTER_RING_FDS
+	IORING_UNREGISTER_RING_FDS
+	IORING_REGISTER_PBUF_RING
+	IORING_UNREGISTER_PBUF_RING
 )
 
 type IOURingFilesUpdate struct {
@@ -30,9 +41,68 @@ type IOURingFilesUpdate struct {
 	Fds    *int32
 }
 
+// IOURingBufReg is the argument of IORING_REGISTER_PBUF_RING
+type IOURingBufReg struct {
+	RingAddr    uint64
+	RingEntries uint32
+	Bgid        uint16
+	Flags       uint16
+	resv        [3]uint64
+}
+
+// IOURingBuf is the entry of the provided buffer ring,
+// the resv field of the first entry is the tail of the ring
+type IOURingBuf struct {
+	Addr uint64
+	Len  uint32
+	Bid  uint16
+	Resv uint16
+}
+
+const IO_URING_OP_SUPPORTED uint16 = 1 << 0
+
+type IOURingProbeOp struct {
+	Op    uint8
+	resv  uint8
+	Flags uint16
+	resv2 uint32
+}
+
+type IOURingProbe struct {
+	LastOp uint8
+	OpsLen uint8
+	resv   uint16
+	resv2  [3]uint32
+	Ops    [256]IOURingProbeOp
+}
+
+// io_uring restriction opcodes
+const (
+	IORING_RESTRICTION_REGISTER_OP uint16 = iota
+	IORING_RESTRICTION_SQE_OP
+	IORING_RESTRICTION_SQE_FLAGS_ALLOWED
+	IORING_RESTRICTION_SQE_FLAGS_REQUIRED
+)
+
+// IOURingRestriction is the argument of IORING_REGISTER_RESTRICTIONS,
+// Arg is the register opcode, the sqe opcode or the sqe flags by Opcode
+type IOURingRestriction struct {
+	Opcode uint16
+	Arg    uint8
+	resv   uint8
+	resv2  [3]uint32
+}
+
 func IOURingRegister(fd int, opcode uint8, args unsafe.Pointer, nrArgs uint32) error {
+	_, err := IOURingRegisterResult(fd, opcode, args, nrArgs)
+	return err
+}
+
+// IOURingRegisterResult is IOURingRegister returning the result of the register operation,
+// such as the personality id of IORING_REGISTER_PERSONALITY
+func IOURingRegisterResult(fd int, opcode uint8, args unsafe.Pointer, nrArgs uint32) (int, error) {
 	for {
-		_, _, errno := syscall.Syscall6(
+		r1, _, errno := syscall.Syscall6(
 			SYS_IO_URING_REGISTER,
 			uintptr(fd),
 			uintptr(opcode),
@@ -46,8 +116,8 @@ func IOURingRegister(fd int, opcode uint8, args unsafe.Pointer, nrArgs uint32) e
 			if errno == syscall.EINTR {
 				continue
 			}
-			return os.NewSyscallError("iouring_register", errno)
+			return 0, os.NewSyscallError("iouring_register", errno)
 		}
-		return nil
+		return int(r1), nil
 	}
 }
@@ -24,6 +24,8 @@ const (
 	IORING_SETUP_TASKRUN_FLAG
 	IORING_SETUP_SQE128
 	IORING_SETUP_CQE32
+	IORING_SETUP_SINGLE_ISSUER
+	IORING_SETUP_DEFER_TASKRUN
 )
 
 // io_uring features supported by current kernel version
@@ -36,6 +38,12 @@ const (
 	IORING_FEAT_FAST_POLL
 	IORING_FEAT_POLL_32BITS
 	IORING_FEAT_SQPOLL_NONFIXED
+	IORING_FEAT_EXT_ARG
+	IORING_FEAT_NATIVE_WORKERS
+	IORING_FEAT_RSRC_TAGS
+	IORING_FEAT_CQE_SKIP
+	IORING_FEAT_LINKED_FILE
+	IORING_FEAT_REG_REG_RING
 )
 
 // IOURingParams the flags, sq_thread_cpu, sq_thread_idle and WQFd fields are used to configure the io_uring instance
@@ -102,14 +102,19 @@ type SubmissionQueueEntry interface {
 	Fd() int32
 	SetFdIndex(index int32)
 	SetOpFlags(opflags uint32)
+	OpFlags() uint32
 	SetUserData(userData uint64)
 	SetFlags(flag uint8)
+	Flags() uint8
 	CleanFlags(flags uint8)
 	SetIoprio(ioprio uint16)
 	SetBufIndex(bufIndex uint16)
+	BufIndex() uint16
 	SetBufGroup(bufGroup uint16)
 	SetPersonality(personality uint16)
 	SetSpliceFdIn(fdIn int32)
+	SpliceFdIn() int32
+	SetAddr3(addr3 uint64)
 
 	CMD(castType interface{}) interface{}
 }
@@ -155,6 +160,10 @@ func (sqe *sqeCore) SetOpFlags(opflags uint32) {
 	sqe.opFlags = opflags
 }
 
+func (sqe *sqeCore) OpFlags() uint32 {
+	return sqe.opFlags
+}
+
 func (sqe *sqeCore) SetUserData(userData uint64) {
 	sqe.userdata = userData
 }
@@ -163,8 +172,12 @@ func (sqe *sqeCore) SetFlags(flags uint8) {
 	sqe.flags |= flags
 }
 
+func (sqe *sqeCore) Flags() uint8 {
+	return sqe.flags
+}
+
 func (sqe *sqeCore) CleanFlags(flags uint8) {
-	sqe.flags ^= flags
+	sqe.flags &^= flags
 }
 
 func (sqe *sqeCore) SetIoprio(ioprio uint16) {
@@ -175,6 +188,10 @@ func (sqe *sqeCore) SetBufIndex(bufIndex uint16) {
 	sqe.bufIndexOrGroup = bufIndex
 }
 
+func (sqe *sqeCore) BufIndex() uint16 {
+	return sqe.bufIndexOrGroup
+}
+
 func (sqe *sqeCore) SetBufGroup(bufGroup uint16) {
 	sqe.bufIndexOrGroup = bufGroup
 }
@@ -187,6 +204,10 @@ func (sqe *sqeCore) SetSpliceFdIn(fdIn int32) {
 	sqe.spliceFdIn = fdIn
 }
 
+func (sqe *sqeCore) SpliceFdIn() int32 {
+	return sqe.spliceFdIn
+}
+
 type SubmissionQueueEntry64 struct {
 	sqeCore
 
@@ -197,6 +218,10 @@ func (sqe *SubmissionQueueEntry64) Reset() {
 	*sqe = SubmissionQueueEntry64{}
 }
 
+func (sqe *SubmissionQueueEntry64) SetAddr3(addr3 uint64) {
+	sqe.extra[0] = addr3
+}
+
 func (sqe *SubmissionQueueEntry64) CMD(_ interface{}) interface{} {
 	panic(fmt.Errorf("unsupported interface for CMD command"))
 }
@@ -211,6 +236,11 @@ func (sqe *SubmissionQueueEntry128) Reset() {
 	*sqe = SubmissionQueueEntry128{}
 }
 
+// SetAddr3 the addr3 field overlays the first 8 bytes of the command
+func (sqe *SubmissionQueueEntry128) SetAddr3(addr3 uint64) {
+	*(*uint64)(unsafe.Pointer(&sqe.cmd[0])) = addr3
+}
+
 func (sqe *SubmissionQueueEntry128) CMD(castType interface{}) interface{} {
 	return reflect.NewAt(reflect.TypeOf(castType), unsafe.Pointer(&sqe.cmd[0])).Interface()
 }
@@ -281,5 +311,37 @@ func (cqe *CompletionQueueEvent32) Clone() CompletionQueueEvent {
 	return dest
 }
 
+// CompletionQueueEvent flags
+const (
+	IORING_CQE_F_BUFFER uint32 = 1 << iota
+	IORING_CQE_F_MORE
+	IORING_CQE_F_SOCK_NONEMPTY
+	IORING_CQE_F_NOTIF
+)
+
+const IORING_CQE_BUFFER_SHIFT = 16
+
+// accept flags stored in SubmissionQueueEntry.ioprio
+const IORING_ACCEPT_MULTISHOT uint16 = 1 << 0
+
+// send/recv flags stored in SubmissionQueueEntry.ioprio
+const (
+	IORING_RECVSEND_POLL_FIRST uint16 = 1 << iota
+	IORING_RECV_MULTISHOT
+	IORING_RECVSEND_FIXED_BUF
+	IORING_SEND_ZC_REPORT_USAGE
+)
+
+// cancel flags stored in SubmissionQueueEntry.opFlags
+const (
+	IORING_ASYNC_CANCEL_ALL uint32 = 1 << iota
+	IORING_ASYNC_CANCEL_FD
+	IORING_ASYNC_CANCEL_ANY
+	IORING_ASYNC_CANCEL_FD_FIXED
+)
+
+// poll flags stored in SubmissionQueueEntry.len
+const IORING_POLL_ADD_MULTI uint32 = 1 << 0
+
 const IORING_FSYNC_DATASYNC uint32 = 1
 const IORING_TIMEOUT_ABS uint32 = 1
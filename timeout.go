@@ -4,6 +4,7 @@
 package iouring
 
 import (
+	"math"
 	"time"
 	"unsafe"
 
@@ -12,14 +13,21 @@ import (
 	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
 
+// WithTimeout link the request with a LinkTimeout,
+// the request will be canceled if it's not completed before the timeout,
+// and the timeout is removed if the request is completed first.
+// The returned requests must be submitted together and kept in order by SubmitRequests,
+// since the LinkTimeout must be placed right after the request
 func (prepReq PrepRequest) WithTimeout(timeout time.Duration) []PrepRequest {
 	linkRequest := func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		prepReq(sqe, userData)
 		sqe.SetFlags(iouring_syscall.IOSQE_FLAGS_IO_LINK)
 	}
-	return []PrepRequest{linkRequest, linkTimeout(timeout)}
+	return []PrepRequest{linkRequest, LinkTimeout(timeout)}
 }
 
+// Timeout is completed when the relative timeout expires,
+// the result returns TimeoutExpiration by ReturnValue0
 func Timeout(t time.Duration) PrepRequest {
 	timespec := unix.NsecToTimespec(t.Nanoseconds())
 
@@ -31,6 +39,8 @@ func Timeout(t time.Duration) PrepRequest {
 	}
 }
 
+// TimeoutWithTime is completed when the absolute time t is reached,
+// the result returns TimeoutExpiration by ReturnValue0
 func TimeoutWithTime(t time.Time) (PrepRequest, error) {
 	timespec, err := unix.TimeToTimespec(t)
 	if err != nil {
@@ -46,18 +56,31 @@ func TimeoutWithTime(t time.Time) (PrepRequest, error) {
 	}, nil
 }
 
-func CountCompletionEvent(n uint64) PrepRequest {
+// TimeoutWithCount is completed when the timeout expires or n requests are completed,
+// the result returns TimeoutExpiration or CountCompletion by ReturnValue0
+func TimeoutWithCount(t time.Duration, n uint64) PrepRequest {
+	timespec := unix.NsecToTimespec(t.Nanoseconds())
+
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
+		userData.hold(&timespec)
 		userData.request.resolver = timeoutResolver
 
-		sqe.PrepOperation(iouring_syscall.IORING_OP_TIMEOUT, -1, 0, 0, n)
+		sqe.PrepOperation(iouring_syscall.IORING_OP_TIMEOUT, -1, uint64(uintptr(unsafe.Pointer(&timespec))), 1, n)
 	}
 }
 
+// CountCompletionEvent is completed when n requests are completed
+func CountCompletionEvent(n uint64) PrepRequest {
+	// the kernel requires a timespec, use the maximum duration
+	return TimeoutWithCount(math.MaxInt64, n)
+}
+
+// RemoveTimeout remove the timeout request of the request id returned by Request.ID,
+// the removed timeout request is completed with ErrRequestCanceled
 func RemoveTimeout(id uint64) PrepRequest {
 	return func(sqe iouring_syscall.SubmissionQueueEntry, userData *UserData) {
 		userData.request.resolver = removeTimeoutResolver
 
-		sqe.PrepOperation(iouring_syscall.IORING_OP_TIMEOUT, -1, id, 0, 0)
+		sqe.PrepOperation(iouring_syscall.IORING_OP_TIMEOUT_REMOVE, -1, id, 0, 0)
 	}
 }
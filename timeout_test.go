@@ -0,0 +1,103 @@
+package iouring
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTimeoutWithCount(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	expiration, err := iour.SubmitRequest(TimeoutWithCount(time.Millisecond, 100), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-expiration.Done()
+	if err := expiration.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v := expiration.ReturnValue0(); v != TimeoutExpiration {
+		t.Fatalf("timeout return value is %v, want TimeoutExpiration", v)
+	}
+
+	completion, err := iour.SubmitRequest(TimeoutWithCount(time.Hour, 2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iour.SubmitRequests([]PrepRequest{Nop(), Nop()}, nil); err != nil {
+		t.Fatal(err)
+	}
+	<-completion.Done()
+	if err := completion.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v := completion.ReturnValue0(); v != CountCompletion {
+		t.Fatalf("timeout return value is %v, want CountCompletion", v)
+	}
+}
+
+func TestLinkTimeout(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	ch := make(chan Result, 2)
+	requests, err := iour.SubmitLinkRequests([]PrepRequest{
+		Read(int(r.Fd()), make([]byte, 1)),
+		LinkTimeout(10 * time.Millisecond),
+	}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-requests.Done()
+
+	if err := (<-ch).Err(); err != ErrRequestCanceled {
+		t.Fatalf("read error is %v, want %v", err, ErrRequestCanceled)
+	}
+	select {
+	case result := <-ch:
+		t.Fatalf("link timeout is notified via channel, opcode %d", result.Opcode())
+	default:
+	}
+}
+
+func TestRemoveTimeout(t *testing.T) {
+	iour, err := New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	timeout, err := iour.SubmitRequest(Timeout(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remove, err := iour.SubmitRequest(RemoveTimeout(timeout.ID()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-remove.Done()
+	if err := remove.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-timeout.Done()
+	if err := timeout.Err(); err != ErrRequestCanceled {
+		t.Fatalf("removed timeout error is %v, want %v", err, ErrRequestCanceled)
+	}
+}
@@ -168,6 +168,10 @@ type SubmissionQueue struct {
 
 	sqeHead uint32
 	sqeTail uint32
+
+	// unflushed is the number of the prepared entries at the tail which are not published by flush,
+	// they are the entries of the incomplete link chain
+	unflushed uint32
 }
 
 func (queue *SubmissionQueue) getSQEntry() iouring_syscall.SubmissionQueueEntry {
@@ -187,6 +191,15 @@ func (queue *SubmissionQueue) fallback(i uint32) {
 	queue.sqeTail -= i
 }
 
+// drop rolls back the last n published entries which are not consumed by the kernel,
+// the entries which are not published are dropped too
+func (queue *SubmissionQueue) drop(n uint32) {
+	atomic.StoreUint32(queue.tail, *queue.tail-n)
+	queue.sqeHead -= n
+	queue.sqeTail = queue.sqeHead
+	queue.unflushed = 0
+}
+
 func (queue *SubmissionQueue) cqOverflow() bool {
 	return (atomic.LoadUint32(queue.flags) & iouring_syscall.IORING_SQ_CQ_OVERFLOW) != 0
 }
@@ -198,12 +211,12 @@ func (queue *SubmissionQueue) needWakeup() bool {
 // sync internal status with kernel ring state on the SQ side
 // return the number of pending items in the SQ ring, for the shared ring.
 func (queue *SubmissionQueue) flush() int {
-	if queue.sqeHead == queue.sqeTail {
+	if queue.sqeTail-queue.sqeHead == queue.unflushed {
 		return int(*queue.tail - *queue.head)
 	}
 
 	tail := *queue.tail
-	for toSubmit := queue.sqeTail - queue.sqeHead; toSubmit > 0; toSubmit-- {
+	for toSubmit := queue.sqeTail - queue.sqeHead - queue.unflushed; toSubmit > 0; toSubmit-- {
 		queue.array[tail&*queue.mask] = queue.sqeHead & *queue.mask
 		tail++
 		queue.sqeHead++
@@ -319,6 +332,21 @@ func (queue *CompletionQueue) peek() (cqe iouring_syscall.CompletionQueueEvent)
 	return
 }
 
+// peekBatch fill cqes with the available completion events without advancing the head,
+// returns the number of the completion events
+func (queue *CompletionQueue) peekBatch(cqes []iouring_syscall.CompletionQueueEvent) int {
+	head := *queue.head
+	n := atomic.LoadUint32(queue.tail) - head
+	if n > uint32(len(cqes)) {
+		n = uint32(len(cqes))
+	}
+
+	for i := uint32(0); i < n; i++ {
+		cqes[i] = queue.cqes.index((head + i) & *queue.mask)
+	}
+	return int(n)
+}
+
 func (queue *CompletionQueue) advance(num uint32) {
 	if num != 0 {
 		atomic.AddUint32(queue.head, num)
@@ -3,7 +3,7 @@
 package iouring
 
 import (
-	"unsafe"
+	"sync/atomic"
 )
 
 type UserData struct {
@@ -14,6 +14,13 @@ type UserData struct {
 
 	holds   []interface{}
 	request *request
+
+	// completed is called by the completion loop after the request is completed
+	completed func()
+
+	// bufGroup is the buffer group of the request with IOSQE_BUFFER_SELECT
+	bufSelect bool
+	bufGroup  uint16
 }
 
 func (data *UserData) SetResultResolver(resolver ResultResolver) {
@@ -56,7 +63,9 @@ func makeUserData(iour *IOURing, ch chan<- Result) *UserData {
 		request:  &request{iour: iour, done: make(chan struct{})},
 	}
 
-	userData.id = uint64(uintptr(unsafe.Pointer(userData)))
+	// request id is allocated from a monotonic counter instead of the address of userData,
+	// iour.userDatas is the owning reference of userData until the request is completed
+	userData.id = atomic.AddUint64(&iour.userDataID, 1)
 	userData.request.id = userData.id
 	return userData
 }
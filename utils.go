@@ -3,8 +3,12 @@
 package iouring
 
 import (
+	"errors"
+	"net"
 	"syscall"
 	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
 )
 
 var zero uintptr
@@ -22,8 +26,125 @@ func bytes2iovec(bs [][]byte) []syscall.Iovec {
 	return iovecs
 }
 
+func addrToSockaddr(addr net.Addr) (syscall.Sockaddr, error) {
+	var ip net.IP
+	var port int
+	var zone string
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip, port, zone = addr.IP, addr.Port, addr.Zone
+	case *net.UDPAddr:
+		ip, port, zone = addr.IP, addr.Port, addr.Zone
+	case *net.UnixAddr:
+		return &syscall.SockaddrUnix{Name: addr.Name}, nil
+	default:
+		return nil, errors.New("unsupported address type")
+	}
+
+	if ip4 := ip.To4(); ip4 != nil && zone == "" {
+		sa := &syscall.SockaddrInet4{Port: port}
+		copy(sa.Addr[:], ip4)
+		return sa, nil
+	}
+
+	sa := &syscall.SockaddrInet6{Port: port}
+	copy(sa.Addr[:], ip.To16())
+	if zone != "" {
+		ifi, err := net.InterfaceByName(zone)
+		if err != nil {
+			return nil, err
+		}
+		sa.ZoneId = uint32(ifi.Index)
+	}
+	return sa, nil
+}
+
 //go:linkname sockaddr syscall.Sockaddr.sockaddr
 func sockaddr(addr syscall.Sockaddr) (unsafe.Pointer, uint32, error)
 
 //go:linkname anyToSockaddr syscall.anyToSockaddr
 func anyToSockaddr(rsa *syscall.RawSockaddrAny) (syscall.Sockaddr, error)
+
+func sockaddrToAddr(sotype int, sa syscall.Sockaddr) net.Addr {
+	var ip net.IP
+	var port int
+	var zone string
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		ip, port = append(net.IP(nil), sa.Addr[:]...), sa.Port
+	case *syscall.SockaddrInet6:
+		ip, port = append(net.IP(nil), sa.Addr[:]...), sa.Port
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				zone = ifi.Name
+			}
+		}
+	case *syscall.SockaddrUnix:
+		network := "unix"
+		if sotype == syscall.SOCK_SEQPACKET {
+			network = "unixpacket"
+		} else if sotype == syscall.SOCK_DGRAM {
+			network = "unixgram"
+		}
+		return &net.UnixAddr{Name: sa.Name, Net: network}
+	default:
+		return nil
+	}
+
+	if sotype == syscall.SOCK_DGRAM {
+		return &net.UDPAddr{IP: ip, Port: port, Zone: zone}
+	}
+	return &net.TCPAddr{IP: ip, Port: port, Zone: zone}
+}
+
+func isReadWriteOp(op uint8) bool {
+	switch op {
+	case iouring_syscall.IORING_OP_READ, iouring_syscall.IORING_OP_WRITE,
+		iouring_syscall.IORING_OP_READV, iouring_syscall.IORING_OP_WRITEV,
+		iouring_syscall.IORING_OP_READ_FIXED, iouring_syscall.IORING_OP_WRITE_FIXED:
+		return true
+	}
+	return false
+}
+
+// isFdNumber returns whether the fd field of the op is a number instead of a file descriptor,
+// such as the number of the buffers of IORING_OP_PROVIDE_BUFFERS
+func isFdNumber(op uint8) bool {
+	switch op {
+	case iouring_syscall.IORING_OP_PROVIDE_BUFFERS, iouring_syscall.IORING_OP_REMOVE_BUFFERS,
+		iouring_syscall.IORING_OP_SOCKET:
+		return true
+	}
+	return false
+}
+
+// canUseFixedFile returns whether the fd field of the op can be a registered file,
+// it's false for the ops without a file and the ops taking a directory fd
+func canUseFixedFile(op uint8) bool {
+	if isFdNumber(op) {
+		return false
+	}
+
+	switch op {
+	case iouring_syscall.IORING_OP_NOP, iouring_syscall.IORING_OP_POLL_REMOVE,
+		iouring_syscall.IORING_OP_TIMEOUT, iouring_syscall.IORING_OP_TIMEOUT_REMOVE,
+		iouring_syscall.IORING_OP_LINK_TIMEOUT, iouring_syscall.IORING_OP_ASYNC_CANCEL,
+		iouring_syscall.IORING_OP_FILES_UPDATE,
+		iouring_syscall.IORING_OP_OPENAT, iouring_syscall.IORING_OP_OPENAT2, iouring_syscall.IORING_OP_STATX,
+		iouring_syscall.IORING_OP_RENAMEAT, iouring_syscall.IORING_OP_UNLINKAT, iouring_syscall.IORING_OP_MKDIRAT,
+		iouring_syscall.IORING_OP_SYMLINKAT, iouring_syscall.IORING_OP_LINKAT,
+		iouring_syscall.IORING_OP_SETXATTR, iouring_syscall.IORING_OP_GETXATTR:
+		return false
+	}
+	return true
+}
+
+// roundupPow2 returns the smallest power of 2 greater than or equal to n,
+// the kernel rounds up the queue sizes by it
+func roundupPow2(n uint) uint {
+	p := uint(1)
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,28 @@
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"unsafe"
+
+	iouring_syscall "github.com/iceber/iouring-go/syscall"
+)
+
+// SetMaxWorkers limit the number of the asynchronous worker threads, bounded workers
+// handle the requests with bounded execution time such as regular file I/O, unbounded
+// workers handle the requests which may never complete such as network I/O.
+// The limit 0 leaves the current value unchanged, the previous limits are returned.
+// IORING_REGISTER_IOWQ_MAX_WORKERS is available since 5.15
+func (iour *IOURing) SetMaxWorkers(bounded, unbounded uint32) (prevBounded, prevUnbounded uint32, err error) {
+	values := [2]uint32{bounded, unbounded}
+	if err := iouring_syscall.IOURingRegister(
+		iour.fd,
+		iouring_syscall.IORING_REGISTER_IOWQ_MAX_WORKERS,
+		unsafe.Pointer(&values[0]),
+		uint32(len(values)),
+	); err != nil {
+		return 0, 0, err
+	}
+	return values[0], values[1], nil
+}
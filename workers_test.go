@@ -0,0 +1,26 @@
+// +build linux
+
+package iouring
+
+import "testing"
+
+func TestSetMaxWorkers(t *testing.T) {
+	iour, err := New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iour.Close()
+
+	if _, _, err := iour.SetMaxWorkers(4, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	// the limits 0 only read the current limits
+	bounded, unbounded, err := iour.SetMaxWorkers(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounded != 4 || unbounded != 8 {
+		t.Fatalf("max workers are %d and %d, want 4 and 8", bounded, unbounded)
+	}
+}